@@ -1,9 +1,12 @@
 package main
 
 import (
+	"encoding/json"
 	"fmt"
 	"os"
+	"os/signal"
 	"strings"
+	"syscall"
 
 	"github.com/VirtusLab/render/constants"
 	"github.com/VirtusLab/render/renderer"
@@ -23,6 +26,33 @@ var (
 	outputDir               string
 	configPaths             cli.StringSlice
 	vars                    cli.StringSlice
+	stringVars              cli.StringSlice
+	jsonVars                cli.StringSlice
+	fileVars                cli.StringSlice
+	envPrefix               string
+	exposeEnv               bool
+	mergeStrategy           string
+	profiles                cli.StringSlice
+	interpolate             bool
+	schemaPath              string
+	requiredPaths           cli.StringSlice
+	explain                 bool
+	paramsOutput            string
+	redact                  bool
+	ignorePatterns          cli.StringSlice
+	dirMode                 string
+	templateExtensions      cli.StringSlice
+	leftDelim               string
+	rightDelim              string
+	extDelims               cli.StringSlice
+	jobs                    int
+	check                   bool
+	diff                    bool
+	prune                   bool
+	atomic                  bool
+	frontMatter             bool
+	foreach                 string
+	watch                   bool
 	unsafeIgnoreMissingKeys bool
 )
 
@@ -68,21 +98,115 @@ func main() {
 			Usage:       "the output file, stdout if empty, can't be used with --indir",
 			Destination: &outputFile,
 		},
+	}
+	app.Flags = append(app.Flags, parameterFlags()...)
+	app.Flags = append(app.Flags, []cli.Flag{
 		cli.StringSliceFlag{
-			Name:  "config",
-			Usage: "optional configuration YAML file, can be used multiple times",
-			Value: &configPaths,
+			Name:  "ignore",
+			Usage: "gitignore-style pattern of paths to skip in the directory mode, in addition to .renderignore files, can be used multiple times",
+			Value: &ignorePatterns,
+		},
+		cli.StringFlag{
+			Name:        "dir-mode",
+			Value:       string(renderer.DefaultDirMode),
+			Usage:       "how to handle files without a template extension in the directory mode: 'all' renders all files, 'copy' copies them verbatim, 'skip' skips them",
+			Destination: &dirMode,
 		},
 		cli.StringSliceFlag{
-			Name:  "set, var",
-			Usage: "additional parameters in key=value format, can be used multiple times",
-			Value: &vars,
+			Name:  "template-ext",
+			Usage: "file extension of the templates in the directory mode, trimmed from the output file names, can be used multiple times (default: .tpl, .tmpl)",
+			Value: &templateExtensions,
+		},
+		cli.StringFlag{
+			Name:        "left-delim",
+			Value:       config.LeftDelim,
+			Usage:       "the left delimiter of the templates, e.g. '[[' to render the files which are Go templates themselves",
+			Destination: &leftDelim,
+		},
+		cli.StringFlag{
+			Name:        "right-delim",
+			Value:       config.RightDelim,
+			Usage:       "the right delimiter of the templates, e.g. ']]'",
+			Destination: &rightDelim,
+		},
+		cli.StringSliceFlag{
+			Name:  "ext-delims",
+			Usage: "the delimiters of the templates with the file names ending with the extension, e.g. '.gotmpl=[[ ]]', can be used multiple times",
+			Value: &extDelims,
+		},
+		cli.IntFlag{
+			Name:        "jobs, j",
+			Value:       1,
+			Usage:       "the number of files rendered concurrently in the directory mode, 0 means the number of CPUs",
+			Destination: &jobs,
+		},
+		cli.BoolFlag{
+			Name:        "check",
+			Usage:       "do not write anything, fail if the rendered output differs from the existing --out or --outdir files",
+			Destination: &check,
+		},
+		cli.BoolFlag{
+			Name:        "diff",
+			Usage:       "the same as --check, but also print a unified diff of the differences to stdout",
+			Destination: &diff,
+		},
+		cli.BoolFlag{
+			Name:        "prune",
+			Usage:       "delete the outputs of the previous --outdir run that no longer have a source, see the " + renderer.ManifestFileName + " file",
+			Destination: &prune,
+		},
+		cli.BoolFlag{
+			Name:        "atomic",
+			Usage:       "render all the --indir files into a staging directory first, and move them to --outdir only if all of them succeed",
+			Destination: &atomic,
+		},
+		cli.BoolFlag{
+			Name:        "front-matter",
+			Usage:       "strip the YAML front matter (between two '---' lines at the beginning) from the templates, its values are the defaults of the parameters, its '" + renderer.FrontMatterOptionsKey + "' key sets the per-file options",
+			Destination: &frontMatter,
+		},
+		cli.StringFlag{
+			Name:        "foreach",
+			Usage:       "render the --in template once per element of the given list parameter, available as .item (and .index), --out is then a template of the output path",
+			Destination: &foreach,
+		},
+		cli.BoolFlag{
+			Name:        "watch, w",
+			Usage:       "keep running and re-render the affected outputs when the templates, the configuration files or the files read with readFile change",
+			Destination: &watch,
 		},
 		cli.BoolFlag{
 			Name:        "unsafe-ignore-missing-keys",
 			Usage:       "do not fail on missing map key and print '<no value>' ('missingkey=invalid')",
 			Destination: &unsafeIgnoreMissingKeys,
 		},
+	}...)
+
+	app.Commands = []cli.Command{
+		{
+			Name:      "params",
+			Usage:     "print the merged parameters as YAML without rendering anything",
+			ArgsUsage: " ",
+			Flags: append(parameterFlags(),
+				cli.StringFlag{
+					Name:        "output, o",
+					Value:       "yaml",
+					Usage:       "the output format: 'yaml' or 'json'",
+					Destination: &paramsOutput,
+				},
+				cli.BoolFlag{
+					Name:        "explain",
+					Usage:       "print every value with its origin (a file and a line, a --set index, env or base) and the values it overrides",
+					Destination: &explain,
+				},
+				cli.BoolFlag{
+					Name:        "redact",
+					Usage:       "replace the values of the secret-looking keys, e.g. password, token or api_key, with '" + parameters.RedactedValue + "'",
+					Destination: &redact,
+				},
+			),
+			Action: paramsAction,
+		},
 	}
 
 	app.CommandNotFound = func(c *cli.Context, command string) {
@@ -110,6 +234,73 @@ func main() {
 	}
 }
 
+// parameterFlags returns the flags used to read the parameters, shared by the render action and the params command
+func parameterFlags() []cli.Flag {
+	return []cli.Flag{
+		cli.StringSliceFlag{
+			Name:  "config",
+			Usage: "optional configuration YAML, JSON, TOML or .env file, detected by the extension or set with a format:path prefix, can be used multiple times",
+			Value: &configPaths,
+		},
+		cli.StringSliceFlag{
+			Name:  "set, var",
+			Usage: "additional parameters in key=value format, the value type is inferred (e.g. numbers, booleans, null, {a,b} lists), can be used multiple times",
+			Value: &vars,
+		},
+		cli.StringSliceFlag{
+			Name:  "set-string",
+			Usage: "additional parameters in key=value format, the value is always a string, can be used multiple times",
+			Value: &stringVars,
+		},
+		cli.StringSliceFlag{
+			Name:  "set-json",
+			Usage: "additional parameters in key=json format, e.g. key='{\"a\":[1,2]}', can be used multiple times",
+			Value: &jsonVars,
+		},
+		cli.StringSliceFlag{
+			Name:  "set-file",
+			Usage: "additional parameters in key=path format, the value is the file content, or the parsed content with a key=@yaml:path or key=@json:path, can be used multiple times",
+			Value: &fileVars,
+		},
+		cli.StringFlag{
+			Name:        "env-prefix",
+			Usage:       "use the environment variables with the given prefix as parameters, e.g. RENDER_DB__HOST becomes .db.host",
+			Destination: &envPrefix,
+		},
+		cli.BoolFlag{
+			Name:        "expose-env",
+			Usage:       "expose all the environment variables by their original names under .env, e.g. .env.HOME, fails if the parameters already have an env key",
+			Destination: &exposeEnv,
+		},
+		cli.StringFlag{
+			Name:        "merge-strategy",
+			Value:       parameters.DefaultMergeStrategy.String(),
+			Usage:       "how to merge the lists of the configuration files and the environment variables: 'replace', 'append' or 'merge-by-key[=field]' (the default field is 'name'), the maps are always merged, a '~delete' value removes the key",
+			Destination: &mergeStrategy,
+		},
+		cli.StringSliceFlag{
+			Name:  "profile",
+			Usage: "merge the profile with the given name from the 'profiles' section of the configuration files over the rest of the file, can be used multiple times",
+			Value: &profiles,
+		},
+		cli.BoolFlag{
+			Name:        "interpolate",
+			Usage:       "resolve the references to other parameters in the parameter values, e.g. url: https://${domain}/api or url: https://{{ .domain }}/api",
+			Destination: &interpolate,
+		},
+		cli.StringSliceFlag{
+			Name:  "required",
+			Usage: "fail before rendering if any of the parameters listed in the file are missing, the file maps the keys to their descriptions (e.g. db.host: the database host), can be used multiple times",
+			Value: &requiredPaths,
+		},
+		cli.StringFlag{
+			Name:        "schema",
+			Usage:       "validate the parameters against a JSON Schema file (JSON or YAML) before rendering, e.g. values.schema.json",
+			Destination: &schemaPath,
+		},
+	}
+}
+
 func preload(c *cli.Context) error {
 	if c.GlobalBool("silent") {
 		logrus.SetLevel(logrus.FatalLevel)
@@ -138,37 +329,35 @@ func preload(c *cli.Context) error {
 }
 
 func action(c *cli.Context) error {
-	opts := []string{config.MissingKeyErrorOption}
-	if unsafeIgnoreMissingKeys {
-		logrus.Warnf("You are using '--unsafe-ignore-missing-keys' and %s will use option '%s'",
-			app.Name, config.MissingKeyInvalidOption)
-		opts = []string{config.MissingKeyInvalidOption}
-	}
-
 	if len(configPaths) > 0 {
 		logrus.Infof("Configurations:\n\t%s", strings.Join(configPaths, "\n\t"))
 	}
 	if len(vars) > 0 {
 		logrus.Infof("Variables:\n\t%s", strings.Join(vars, "\n\t"))
 	}
-	params, err := parameters.All(configPaths, vars)
+	r, err := newRenderer()
 	if err != nil {
 		return err
 	}
-
-	r := renderer.New(
-		renderer.WithOptions(opts...),
-		renderer.WithParameters(params),
-		renderer.WithSprigFunctions(),
-		renderer.WithExtraFunctions(),
-		renderer.WithCryptFunctions(),
-		renderer.WithNetFunctions(),
-	)
+	if diff {
+		r.Configure(renderer.WithCheck(os.Stdout))
+	} else if check {
+		r.Configure(renderer.WithCheck(nil))
+	}
 
 	// check for extra args after vars and configs were parsed to avoid confusing error messages
 	if c.NArg() > 0 {
 		return fmt.Errorf("have not expected any arguments, got %d", c.NArg())
 	}
+	if watch && (check || diff) {
+		return fmt.Errorf("conflict, --watch can't be used with --check or --diff")
+	}
+	if watch && (prune || atomic) {
+		return fmt.Errorf("conflict, --watch can't be used with --prune or --atomic")
+	}
+	if watch && len(foreach) > 0 {
+		return fmt.Errorf("conflict, --watch can't be used with --foreach")
+	}
 	if len(inputDir) > 0 {
 		if len(inputFile) > 0 {
 			return fmt.Errorf("conflict, --in can't be used with --indir or --outdir")
@@ -176,14 +365,22 @@ func action(c *cli.Context) error {
 		if len(outputFile) > 0 {
 			return fmt.Errorf("conflict, --out can't be used with --indir or --outdir")
 		}
+		if len(foreach) > 0 {
+			return fmt.Errorf("conflict, --foreach can't be used with --indir or --outdir")
+		}
 		if len(outputDir) == 0 {
 			outputDir = inputDir
 		}
+		if watch {
+			return renderer.NewWatcher(newRenderer, configPaths...).WatchDir(inputDir, outputDir, stopOnSignal())
+		}
 
 		err = r.DirRender(inputDir, outputDir)
 		switch err.(type) {
 		case nil:
 			return nil
+		case *renderer.DriftError:
+			return cli.NewExitError(err.Error(), 1)
 		default:
 			return err
 		}
@@ -195,13 +392,247 @@ func action(c *cli.Context) error {
 	if len(outputDir) > 0 {
 		return fmt.Errorf("conflict, --outdir can't be used with --in or --out")
 	}
-	err = r.FileRender(inputFile, outputFile)
+	if prune || atomic {
+		return fmt.Errorf("--prune and --atomic require --indir")
+	}
+	if (check || diff) && len(outputFile) == 0 {
+		return fmt.Errorf("--check and --diff require --out or --outdir to compare with")
+	}
+	if watch {
+		if len(inputFile) == 0 || len(outputFile) == 0 {
+			return fmt.Errorf("--watch requires --in and --out or --indir")
+		}
+		return renderer.NewWatcher(newRenderer, configPaths...).WatchFile(inputFile, outputFile, stopOnSignal())
+	}
+	if len(foreach) > 0 {
+		if len(outputFile) == 0 {
+			return fmt.Errorf("--foreach requires --out with a template of the output path")
+		}
+		err = r.ForeachRender(inputFile, foreach, outputFile)
+	} else {
+		err = r.FileRender(inputFile, outputFile)
+	}
 	switch err.(type) {
 	case nil:
 		return nil
 	case *files.ErrExpectedStdin:
 		return fmt.Errorf("expected either stdin, --indir or --in parameter, for usage use --help")
+	case *renderer.DriftError:
+		return cli.NewExitError(err.Error(), 1)
 	default:
 		return err
 	}
 }
+
+// paramsAction prints the merged parameters as YAML or JSON, or every value with its origin with --explain
+func paramsAction(c *cli.Context) error {
+	if c.NArg() > 0 {
+		return fmt.Errorf("have not expected any arguments, got %d", c.NArg())
+	}
+	if paramsOutput != "yaml" && paramsOutput != "json" {
+		return fmt.Errorf("unexpected output format: '%s', format must be in: [yaml json]", paramsOutput)
+	}
+	params, origins, err := newParameters(explain)
+	if err != nil {
+		return err
+	}
+	if redact {
+		params = params.Redact(parameters.SecretKeyPattern)
+		origins = origins.Redact(parameters.SecretKeyPattern)
+	}
+
+	if explain {
+		return origins.Explain(os.Stdout, params)
+	}
+	if paramsOutput == "json" {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetEscapeHTML(false)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(params)
+	}
+	result, err := renderer.ToYAML(params)
+	if err != nil {
+		return err
+	}
+	fmt.Print(result)
+	return nil
+}
+
+// newParameters reads and validates the parameters configured with the flags,
+// the origins of the values are tracked only if necessary, otherwise they are nil
+func newParameters(track bool) (parameters.Parameters, parameters.Origins, error) {
+	strategy, err := parameters.ParseMergeStrategy(mergeStrategy)
+	if err != nil {
+		return nil, nil, err
+	}
+	strategy.Profiles = profiles
+
+	var origins parameters.Origins
+	if track || len(schemaPath) > 0 {
+		origins = parameters.Origins{}
+		strategy.Origins = origins
+	}
+
+	params, err := strategy.All(configPaths, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(envPrefix) > 0 {
+		env, err := parameters.FromEnv(envPrefix)
+		if err != nil {
+			return nil, nil, err
+		}
+		before := params
+		params, err = strategy.Merge(params, env)
+		if err != nil {
+			return nil, nil, err
+		}
+		origins.Track("env", before, params)
+	}
+
+	// the variables are set on top of the other parameters, in the order of precedence
+	root, _ := params[parameters.RootKey].(string)
+	setters := []struct {
+		flag string
+		vars []string
+		set  func(vars []string) error
+	}{
+		{"--set-json", jsonVars, params.SetJSONVars},
+		{"--set-file", fileVars, func(vars []string) error { return params.SetFileVars(root, vars) }},
+		{"--set-string", stringVars, params.SetStringVars},
+		{"--set", vars, params.SetVars},
+	}
+	for _, setter := range setters {
+		err = origins.TrackVars(setter.flag, params, setter.vars, setter.set)
+		if err != nil {
+			return nil, nil, fmt.Errorf("can't parse %s: %v", setter.flag, err)
+		}
+	}
+	if exposeEnv {
+		before := params.Copy()
+		err = params.SetEnv()
+		if err != nil {
+			return nil, nil, err
+		}
+		origins.Track("--expose-env", before, params)
+	}
+
+	if interpolate {
+		// the templates in the values are rendered with the same options, delimiters and functions as the files
+		configurators, err := templateConfigurators()
+		if err != nil {
+			return nil, nil, err
+		}
+		r := renderer.New(configurators...)
+		err = params.InterpolateWithDelims(leftDelim, rightDelim, func(data map[string]interface{}, text string) (string, error) {
+			return r.NestedRender(data, text)
+		})
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	requirements, err := parameters.RequirementsFromFiles(requiredPaths)
+	if err != nil {
+		return nil, nil, err
+	}
+	err = params.Validate(requirements...)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if len(schemaPath) > 0 {
+		err = parameters.ValidateAgainstSchema(params, schemaPath, origins)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	return params, origins, nil
+}
+
+// newRenderer reads the parameters and creates a new renderer configured with the flags
+func newRenderer() (renderer.Renderer, error) {
+	if unsafeIgnoreMissingKeys {
+		logrus.Warnf("You are using '--unsafe-ignore-missing-keys' and %s will use option '%s'",
+			app.Name, config.MissingKeyInvalidOption)
+	}
+
+	params, _, err := newParameters(false)
+	if err != nil {
+		return nil, err
+	}
+
+	configurators, err := templateConfigurators()
+	if err != nil {
+		return nil, err
+	}
+	configurators = append(configurators, renderer.WithParameters(params))
+	options := []renderer.Option{
+		renderer.WithIgnorePatterns(ignorePatterns...),
+		renderer.WithDirMode(renderer.DirMode(dirMode)),
+		renderer.WithTemplateExtensions(templateExtensions...),
+		renderer.WithJobs(jobs),
+	}
+	for _, value := range extDelims {
+		extension, left, right, err := parseExtDelims(value)
+		if err != nil {
+			return nil, err
+		}
+		options = append(options, renderer.WithExtensionDelims(extension, left, right))
+	}
+	if prune {
+		options = append(options, renderer.WithPrune())
+	}
+	if atomic {
+		options = append(options, renderer.WithAtomic())
+	}
+	if frontMatter {
+		options = append(options, renderer.WithFrontMatter())
+	}
+	return renderer.NewWithOptions(options, configurators...), nil
+}
+
+// templateConfigurators returns the template options, delimiters and functions configured with the flags,
+// shared by the files and the interpolated parameter values
+func templateConfigurators() ([]func(*config.Config), error) {
+	opts := []string{config.MissingKeyErrorOption}
+	if unsafeIgnoreMissingKeys {
+		opts = []string{config.MissingKeyInvalidOption}
+	}
+	if len(leftDelim) == 0 || len(rightDelim) == 0 {
+		return nil, fmt.Errorf("--left-delim and --right-delim can't be empty")
+	}
+	return []func(*config.Config){
+		renderer.WithOptions(opts...),
+		renderer.WithDelim(leftDelim, rightDelim),
+		renderer.WithSprigFunctions(),
+		renderer.WithExtraFunctions(),
+		renderer.WithCryptFunctions(),
+		renderer.WithNetFunctions(),
+	}, nil
+}
+
+// parseExtDelims parses the extension and the left and right delimiters, e.g. '.gotmpl=[[ ]]'
+func parseExtDelims(value string) (string, string, string, error) {
+	i := strings.Index(value, "=")
+	if i <= 0 {
+		return "", "", "", fmt.Errorf("invalid --ext-delims '%s', expected an extension and two delimiters, e.g. '.gotmpl=[[ ]]'", value)
+	}
+	delims := strings.Fields(value[i+1:])
+	if len(delims) != 2 {
+		return "", "", "", fmt.Errorf("invalid --ext-delims '%s', expected an extension and two delimiters, e.g. '.gotmpl=[[ ]]'", value)
+	}
+	return strings.TrimSpace(value[:i]), delims[0], delims[1], nil
+}
+
+// stopOnSignal returns a channel closed on the first interrupt or termination signal
+func stopOnSignal() <-chan struct{} {
+	stop := make(chan struct{})
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-signals
+		close(stop)
+	}()
+	return stop
+}
@@ -168,6 +168,17 @@ func TestDirRender(t *testing.T) {
 	assert.Contains(t, string(expectedSub), stdout, "name: render")
 }
 
+func TestDiff(t *testing.T) {
+	stdout, stderr, err := run("--config", "examples/example.config.yaml",
+		"--in", "examples/cidr.yaml.tmpl", "--out", "examples/example.yaml.expected", "--diff")
+	assert.EqualError(t, err, "exit status 1")
+	assert.Contains(t, stdout, "--- examples/example.yaml.expected\n+++ examples/example.yaml.expected\n")
+	assert.Contains(t, stderr, "the rendered output differs from 1 file(s)")
+
+	_, _, err = run("--in", "examples/cidr.yaml.tmpl", "--out", "examples/cidr.yaml.expected", "--check")
+	assert.NoError(t, err)
+}
+
 func TestNoArgs(t *testing.T) {
 	stdout, stderr, err := run()
 	assert.EqualError(t, err, "exit status 1")
@@ -214,6 +225,18 @@ func TestMissingKeyInvalid(t *testing.T) {
 	assert.NotContains(t, stderr, "error")
 }
 
+func TestInterpolateDelims(t *testing.T) {
+	stdin := "[[ .url ]]"
+	stdout, _, err := runStdin(&stdin,
+		"--left-delim", "[[", "--right-delim", "]]", "--interpolate", "--unsafe-ignore-missing-keys",
+		"--set", "url=https://[[ .domain ]][[ .missing ]]/api",
+		"--set", "domain=example.com",
+	)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "https://example.com<no value>/api", stdout)
+}
+
 func TestVars(t *testing.T) {
 	stdin := `{{ .first }} {{ .second }} {{ .third.nested }}`
 	stdout, _, err := runStdinDebug(&stdin,
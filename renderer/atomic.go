@@ -0,0 +1,190 @@
+package renderer
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// WithAtomic mutates Renderer configuration by enabling the atomic directory mode, in which DirRender
+// renders all the files into a staging directory next to the output directory first, and moves them
+// into place only if all of them succeed, on any error, including an error moving the files,
+// the existing outputs are left untouched or restored
+func WithAtomic() Option {
+	return func(c *extraConfig) {
+		c.atomic = true
+	}
+}
+
+// createStagingDir creates a hidden staging directory next to the output directory,
+// so the staged files can be renamed into place on the same file system
+func createStagingDir(outputDir string) (string, error) {
+	absOutputDir, err := filepath.Abs(outputDir)
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+	parent := filepath.Dir(absOutputDir)
+	err = os.MkdirAll(parent, os.ModePerm)
+	if err != nil {
+		return "", errors.Wrapf(err, "can't create the directory: '%s'", parent)
+	}
+	stagingDir, err := ioutil.TempDir(parent, "."+filepath.Base(absOutputDir)+".render-staging-")
+	if err != nil {
+		return "", errors.Wrapf(err, "can't create a staging directory in: '%s'", parent)
+	}
+	logrus.Debugf("Staging directory was created: '%s'", stagingDir)
+	return stagingDir, nil
+}
+
+func removeStagingDir(stagingDir string) {
+	err := os.RemoveAll(stagingDir)
+	if err != nil {
+		logrus.Warnf("Can't remove the staging directory '%s': %v", stagingDir, err)
+		return
+	}
+	logrus.Debugf("Staging directory was removed: '%s'", stagingDir)
+}
+
+// staged returns the task with the output path moved from the output directory to the staging directory,
+// the task is returned as is if there is no staging directory
+func (task dirTask) staged(outputDir, stagingDir string) (dirTask, error) {
+	if len(stagingDir) == 0 {
+		return task, nil
+	}
+	rel, err := filepath.Rel(outputDir, task.outputPath)
+	if err != nil {
+		return task, errors.Wrapf(err, "can't get a relative path for: '%s'", task.outputPath)
+	}
+	task.outputPath = filepath.Join(stagingDir, rel)
+	return task, nil
+}
+
+// rename is os.Rename, replaced in the tests to simulate the failures
+var rename = os.Rename
+
+// committed is an output moved into place by commitStaged
+type committed struct {
+	outputPath string
+	backupPath string // the replaced output, empty if there was none
+	createdDir string // the top directory created for the output, empty if none
+	moved      bool   // the staged output was moved into place
+}
+
+// commitStaged moves the staged outputs of the tasks into place, the replaced outputs are moved
+// to a backup directory first, so on any error all the outputs moved so far are rolled back
+func commitStaged(outputDir, stagingDir string, tasks []dirTask) error {
+	logrus.Infof("Moving the staged files: '%s' -> '%s'", stagingDir, outputDir)
+	backupDir, err := createStagingDir(outputDir)
+	if err != nil {
+		return err
+	}
+
+	var done []committed
+	for _, task := range tasks {
+		c, err := commitTask(outputDir, stagingDir, backupDir, task)
+		done = append(done, c)
+		if err != nil {
+			logrus.Infof("Rolling back the moved files in '%s'", outputDir)
+			if !rollback(done) {
+				return errors.Wrapf(err, "can't roll back all the moved files, the replaced files are kept in '%s'", backupDir)
+			}
+			removeStagingDir(backupDir)
+			return err
+		}
+	}
+	removeStagingDir(backupDir)
+	return nil
+}
+
+// commitTask moves the staged output of the task into place, the replaced output is moved to the backup directory,
+// the returned state is used to roll back the task, also if it fails partway through
+func commitTask(outputDir, stagingDir, backupDir string, task dirTask) (committed, error) {
+	c := committed{outputPath: task.outputPath}
+	staged, err := task.staged(outputDir, stagingDir)
+	if err != nil {
+		return c, err
+	}
+
+	targetDir := filepath.Dir(task.outputPath)
+	for dir := targetDir; ; dir = filepath.Dir(dir) {
+		_, err := os.Stat(dir)
+		if err == nil {
+			break
+		} else if !os.IsNotExist(err) {
+			return c, errors.Wrapf(err, "can't get file information for '%s'", dir)
+		}
+		c.createdDir = dir
+		if filepath.Dir(dir) == dir {
+			break
+		}
+	}
+	if len(c.createdDir) > 0 {
+		err := os.MkdirAll(targetDir, os.ModePerm)
+		if err != nil {
+			return c, errors.Wrapf(err, "can't create the target directory: '%s'", targetDir)
+		}
+		logrus.Infof("Target directory was created: '%s'", targetDir)
+	}
+
+	info, err := os.Lstat(task.outputPath)
+	if err == nil {
+		if info.IsDir() {
+			return c, errors.Errorf("can't replace the directory with the staged file: '%s'", task.outputPath)
+		}
+		backup, err := task.staged(outputDir, backupDir)
+		if err != nil {
+			return c, err
+		}
+		err = os.MkdirAll(filepath.Dir(backup.outputPath), os.ModePerm)
+		if err != nil {
+			return c, errors.Wrapf(err, "can't create the backup directory: '%s'", filepath.Dir(backup.outputPath))
+		}
+		err = rename(task.outputPath, backup.outputPath)
+		if err != nil {
+			return c, errors.Wrapf(err, "can't back up the file: '%s'", task.outputPath)
+		}
+		c.backupPath = backup.outputPath
+	} else if !os.IsNotExist(err) {
+		return c, errors.Wrapf(err, "can't get file information for '%s'", task.outputPath)
+	}
+
+	logrus.Debugf("Moving '%s' -> '%s'", staged.outputPath, task.outputPath)
+	err = rename(staged.outputPath, task.outputPath)
+	if err != nil {
+		return c, errors.Wrapf(err, "can't move the staged file into place: '%s'", task.outputPath)
+	}
+	c.moved = true
+	return c, nil
+}
+
+// rollback removes the moved outputs, moves the replaced outputs back from the backup directory
+// and removes the created directories, in the reverse order, returns false if anything can't be rolled back
+func rollback(done []committed) bool {
+	ok := true
+	for i := len(done) - 1; i >= 0; i-- {
+		c := done[i]
+		if c.moved {
+			err := os.Remove(c.outputPath)
+			if err != nil && !os.IsNotExist(err) {
+				logrus.Errorf("Can't roll back '%s': %v", c.outputPath, err)
+				ok = false
+				continue
+			}
+		}
+		if len(c.backupPath) > 0 {
+			logrus.Debugf("Restoring '%s'", c.outputPath)
+			err := rename(c.backupPath, c.outputPath)
+			if err != nil {
+				logrus.Errorf("Can't restore '%s': %v", c.outputPath, err)
+				ok = false
+			}
+		}
+		if len(c.createdDir) > 0 {
+			removeEmptyDirs(filepath.Dir(c.createdDir), filepath.Dir(c.outputPath))
+		}
+	}
+	return ok
+}
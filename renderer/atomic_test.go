@@ -0,0 +1,105 @@
+package renderer
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRenderer_DirRender_Atomic(t *testing.T) {
+	params := map[string]interface{}{"value": "new"}
+
+	Run(t, Test{
+		name: "atomic dir render",
+		f: func(tt Test) {
+			inputDir, outputDir := tempDirs(t)
+			defer cleanup(inputDir, outputDir)
+			writeTree(t, inputDir, map[string]string{
+				"a.tmpl":     "{{ .value }}",
+				"sub/b.tmpl": "{{ .value }}",
+				"c.txt":      "copied",
+			})
+			writeTree(t, outputDir, map[string]string{"a": "old", "foreign": "not ours"})
+
+			err := NewWithOptions([]Option{WithAtomic(), WithJobs(2)}, WithParameters(params)).DirRender(inputDir, outputDir)
+
+			assert.NoError(t, err, tt.name)
+			assert.Equal(t, map[string]string{
+				"a":       "new",
+				"sub/b":   "new",
+				"c.txt":   "copied",
+				"foreign": "not ours",
+			}, readTree(t, outputDir))
+			assertNoStagingDir(t, outputDir)
+			assert.Equal(t, 0, CountProblems(tt.logHook))
+		},
+	})
+
+	Run(t, Test{
+		name: "atomic dir render with errors",
+		f: func(tt Test) {
+			inputDir, outputDir := tempDirs(t)
+			defer cleanup(inputDir, outputDir)
+			writeTree(t, inputDir, map[string]string{
+				"a.tmpl":     "{{ .value }}",
+				"b.tmpl":     "{{ broken",
+				"sub/c.tmpl": "{{ .value }}",
+			})
+			writeTree(t, outputDir, map[string]string{"a": "old", "b": "old"})
+
+			err := NewWithOptions([]Option{WithAtomic()}, WithParameters(params)).DirRender(inputDir, outputDir)
+
+			assert.IsType(t, FileErrors{}, err, tt.name)
+			assert.Len(t, err, 1)
+			assert.Equal(t, map[string]string{"a": "old", "b": "old"}, readTree(t, outputDir))
+			assertNoStagingDir(t, outputDir)
+		},
+	})
+
+	Run(t, Test{
+		name: "atomic dir render with errors moving the files",
+		f: func(tt Test) {
+			inputDir, outputDir := tempDirs(t)
+			defer cleanup(inputDir, outputDir)
+			writeTree(t, inputDir, map[string]string{
+				"a.tmpl":     "{{ .value }}",
+				"c.txt":      "copied",
+				"sub/b.tmpl": "{{ .value }}",
+			})
+			writeTree(t, outputDir, map[string]string{"a": "old", "foreign": "not ours"})
+
+			defer func() { rename = os.Rename }()
+			rename = func(oldPath, newPath string) error {
+				if strings.HasSuffix(newPath, filepath.Join(filepath.Base(outputDir), "sub", "b")) {
+					return &os.LinkError{Op: "rename", Old: oldPath, New: newPath, Err: os.ErrPermission}
+				}
+				return os.Rename(oldPath, newPath)
+			}
+
+			err := NewWithOptions([]Option{WithAtomic()}, WithParameters(params)).DirRender(inputDir, outputDir)
+
+			assert.Error(t, err, tt.name)
+			assert.Equal(t, map[string]string{"a": "old", "foreign": "not ours"}, readTree(t, outputDir))
+			_, err = os.Stat(filepath.Join(outputDir, "sub"))
+			assert.True(t, os.IsNotExist(err), "the created directory should be removed")
+			assertNoStagingDir(t, outputDir)
+			assert.Equal(t, 0, CountProblems(tt.logHook))
+		},
+	})
+}
+
+// assertNoStagingDir checks that no staging directory was left next to the output directory
+func assertNoStagingDir(t *testing.T, outputDir string) {
+	entries, err := ioutil.ReadDir(filepath.Dir(outputDir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	prefix := "." + filepath.Base(outputDir) + ".render-staging-"
+	for _, entry := range entries {
+		assert.NotContains(t, entry.Name(), prefix)
+	}
+}
@@ -0,0 +1,87 @@
+package renderer
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/pkg/errors"
+	"github.com/pmezard/go-difflib/difflib"
+	"github.com/sirupsen/logrus"
+)
+
+// WithCheck mutates Renderer configuration by enabling the check mode, in which FileRender and DirRender
+// do not write anything, but compare the rendered output with the existing files instead,
+// the unified diffs are written to the given writer, if not nil, see also DriftError
+func WithCheck(diff io.Writer) Option {
+	return func(c *extraConfig) {
+		c.check = true
+		c.diff = diff
+	}
+}
+
+// DriftError is returned in the check mode if the rendered output differs from the existing files
+type DriftError struct {
+	Paths []string
+}
+
+func (e *DriftError) Error() string {
+	return fmt.Sprintf("the rendered output differs from %d file(s):\n\t%s", len(e.Paths), strings.Join(e.Paths, "\n\t"))
+}
+
+// checkOutput compares the expected content with the existing output file and writes a unified diff
+// if there is a difference, a missing output file is treated as empty
+func checkOutput(outputPath string, expected []byte, diff io.Writer) (bool, error) {
+	logrus.Infof("Checking '%s'", outputPath)
+
+	fromFile := outputPath
+	actual, err := ioutil.ReadFile(outputPath)
+	if os.IsNotExist(err) {
+		fromFile = os.DevNull
+	} else if err != nil {
+		return false, errors.Wrapf(err, "can't read the existing file: '%s'", outputPath)
+	}
+
+	if bytes.Equal(actual, expected) {
+		return false, nil
+	}
+	logrus.Debugf("Drift detected: '%s'", outputPath)
+	if diff == nil {
+		return true, nil
+	}
+
+	if isBinary(actual) || isBinary(expected) {
+		_, err = fmt.Fprintf(diff, "Binary files %s and %s differ\n", fromFile, outputPath)
+		return true, errors.WithStack(err)
+	}
+	err = difflib.WriteUnifiedDiff(diff, difflib.UnifiedDiff{
+		A:        splitLines(string(actual)),
+		B:        splitLines(string(expected)),
+		FromFile: fromFile,
+		ToFile:   outputPath,
+		Context:  3,
+	})
+	return true, errors.WithStack(err)
+}
+
+func isBinary(content []byte) bool {
+	return bytes.IndexByte(content, 0) >= 0 || !utf8.Valid(content)
+}
+
+// splitLines splits the content into lines for a diff, a missing newline at the end is marked the way diff does it
+func splitLines(content string) []string {
+	if len(content) == 0 {
+		return nil
+	}
+	lines := strings.SplitAfter(content, "\n")
+	if last := lines[len(lines)-1]; len(last) == 0 {
+		lines = lines[:len(lines)-1]
+	} else {
+		lines[len(lines)-1] = last + "\n\\ No newline at end of file\n"
+	}
+	return lines
+}
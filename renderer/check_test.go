@@ -0,0 +1,93 @@
+package renderer
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRenderer_FileRender_Check(t *testing.T) {
+	params := map[string]interface{}{"value": "some"}
+
+	Run(t, Test{
+		name: "file check without drift",
+		f: func(tt Test) {
+			inputDir, outputDir := tempDirs(t)
+			defer cleanup(inputDir, outputDir)
+			writeTree(t, inputDir, map[string]string{"a.tmpl": "key: {{ .value }}"})
+			writeTree(t, outputDir, map[string]string{"a": "key: some"})
+
+			var diff bytes.Buffer
+			err := NewWithOptions([]Option{WithCheck(&diff)}, WithParameters(params)).
+				FileRender(filepath.Join(inputDir, "a.tmpl"), filepath.Join(outputDir, "a"))
+
+			assert.NoError(t, err, tt.name)
+			assert.Equal(t, "", diff.String())
+			assert.Equal(t, 0, CountProblems(tt.logHook))
+		},
+	})
+
+	Run(t, Test{
+		name: "file check with drift",
+		f: func(tt Test) {
+			inputDir, outputDir := tempDirs(t)
+			defer cleanup(inputDir, outputDir)
+			writeTree(t, inputDir, map[string]string{"a.tmpl": "first\nkey: {{ .value }}\n"})
+			writeTree(t, outputDir, map[string]string{"a": "first\nkey: other\n"})
+			output := filepath.Join(outputDir, "a")
+
+			var diff bytes.Buffer
+			err := NewWithOptions([]Option{WithCheck(&diff)}, WithParameters(params)).
+				FileRender(filepath.Join(inputDir, "a.tmpl"), output)
+
+			assert.Equal(t, &DriftError{Paths: []string{output}}, err, tt.name)
+			assert.Equal(t, "--- "+output+"\n+++ "+output+"\n@@ -1,2 +1,2 @@\n first\n-key: other\n+key: some\n"+
+				"\\ No newline at end of file\n", diff.String())
+			assert.Equal(t, map[string]string{"a": "first\nkey: other\n"}, readTree(t, outputDir))
+		},
+	})
+}
+
+func TestRenderer_DirRender_Check(t *testing.T) {
+	Run(t, Test{
+		name: "dir check with drift",
+		f: func(tt Test) {
+			inputDir, outputDir := tempDirs(t)
+			defer cleanup(inputDir, outputDir)
+			writeTree(t, inputDir, map[string]string{
+				"same.tmpl":    "{{ .value }}",
+				"changed.tmpl": "{{ .value }}",
+				"missing.tmpl": "{{ .value }}",
+				"copied.bin":   "\x00\x01",
+			})
+			writeTree(t, outputDir, map[string]string{
+				"same":       "some",
+				"changed":    "other",
+				"copied.bin": "\x00\x02",
+			})
+
+			var diff bytes.Buffer
+			err := NewWithOptions(
+				[]Option{WithCheck(&diff), WithJobs(2)},
+				WithParameters(map[string]interface{}{"value": "some"}),
+			).DirRender(inputDir, outputDir)
+
+			assert.Equal(t, &DriftError{Paths: []string{
+				filepath.Join(outputDir, "changed"),
+				filepath.Join(outputDir, "copied.bin"),
+				filepath.Join(outputDir, "missing"),
+			}}, err, tt.name)
+			assert.Contains(t, diff.String(), "-other\n")
+			assert.Contains(t, diff.String(), "Binary files")
+			assert.Contains(t, diff.String(), "--- "+os.DevNull+"\n")
+			assert.Equal(t, map[string]string{
+				"same":       "some",
+				"changed":    "other",
+				"copied.bin": "\x00\x02",
+			}, readTree(t, outputDir), "nothing should be written")
+		},
+	})
+}
@@ -0,0 +1,44 @@
+package renderer
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// extensionDelims are the delimiters of the templates with the extension, see also WithExtensionDelims
+type extensionDelims struct {
+	extension string
+	left      string
+	right     string
+}
+
+// WithExtensionDelims mutates Renderer configuration by setting the left and right delimiters of the templates
+// with the file names ending with the extension, e.g. '.gotmpl' or '.yaml.j2', the leading dot is optional,
+// the longest matching extension wins, the delimiters set in the front matter take precedence,
+// see also WithDelim and WithFrontMatter
+func WithExtensionDelims(extension, left, right string) Option {
+	if !strings.HasPrefix(extension, ".") {
+		extension = "." + extension
+	}
+	return func(c *extraConfig) {
+		delims := make([]extensionDelims, 0, len(c.extensionDelims)+1)
+		for _, d := range c.extensionDelims {
+			if d.extension != extension {
+				delims = append(delims, d)
+			}
+		}
+		c.extensionDelims = append(delims, extensionDelims{extension: extension, left: left, right: right})
+	}
+}
+
+// delimsOf returns the delimiters of the template with the longest matching extension, false if none match
+func (r *renderer) delimsOf(inputPath string) (string, string, bool) {
+	name := filepath.Base(inputPath)
+	var match extensionDelims
+	for _, d := range r.extra.extensionDelims {
+		if len(d.extension) > len(match.extension) && len(name) > len(d.extension) && strings.HasSuffix(name, d.extension) {
+			match = d
+		}
+	}
+	return match.left, match.right, len(match.extension) > 0
+}
@@ -0,0 +1,43 @@
+package renderer
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRenderer_DirRender_ExtensionDelims(t *testing.T) {
+	Run(t, Test{
+		name: "delimiters by the extension",
+		f: func(tt Test) {
+			inputDir, outputDir := tempDirs(t)
+			defer cleanup(inputDir, outputDir)
+			writeTree(t, inputDir, map[string]string{
+				"{{ .name }}.txt.tmpl": "<< .name >> {{ .name }}",
+				"chart.yaml.gotmpl":    "[[ .name ]] {{ .Values.x }}",
+				"values.gotmpl":        "[[ .name ]] <% .name %>",
+				"front.gotmpl":         "---\nrender:\n  delims: ['<%', '%>']\n---\n[[ .name ]] <% .name %>",
+			})
+
+			err := NewWithOptions(
+				[]Option{
+					WithTemplateExtensions(".tmpl", ".gotmpl"),
+					WithExtensionDelims("gotmpl", "<%", "%>"),
+					WithExtensionDelims(".gotmpl", "[[", "]]"),
+					WithExtensionDelims(".yaml.gotmpl", "[[", "]]"),
+					WithFrontMatter(),
+				},
+				WithParameters(map[string]interface{}{"name": "a"}),
+				WithDelim("<<", ">>"),
+			).DirRender(inputDir, outputDir)
+
+			assert.NoError(t, err, tt.name)
+			assert.Equal(t, map[string]string{
+				"{{ .name }}.txt": "a {{ .name }}",
+				"chart.yaml":      "a {{ .Values.x }}",
+				"values":          "a <% .name %>",
+				"front":           "[[ .name ]] a",
+			}, readTree(t, outputDir))
+		},
+	})
+}
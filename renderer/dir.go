@@ -0,0 +1,559 @@
+package renderer
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// DirMode defines how the directory mode handles the files without a template extension
+type DirMode string
+
+const (
+	// RenderAllMode renders all the files, regardless of the extension
+	RenderAllMode DirMode = "all"
+	// CopyNonTemplatesMode renders only the templates and copies the rest of the files verbatim
+	CopyNonTemplatesMode DirMode = "copy"
+	// SkipNonTemplatesMode renders only the templates and skips the rest of the files
+	SkipNonTemplatesMode DirMode = "skip"
+	// DefaultDirMode is the directory mode used if none is set
+	DefaultDirMode = CopyNonTemplatesMode
+)
+
+// DirModes returns all the supported directory modes
+func DirModes() []DirMode {
+	return []DirMode{RenderAllMode, CopyNonTemplatesMode, SkipNonTemplatesMode}
+}
+
+// WithIgnorePatterns mutates Renderer configuration by appending gitignore-style patterns
+// of paths to be skipped in the directory mode, in addition to the patterns from '.renderignore' files
+func WithIgnorePatterns(patterns ...string) Option {
+	return func(c *extraConfig) {
+		c.ignorePatterns = append(append([]string{}, c.ignorePatterns...), patterns...)
+	}
+}
+
+// WithDirMode mutates Renderer configuration by setting how the directory mode
+// handles the files without a template extension, see also DirModes
+func WithDirMode(mode DirMode) Option {
+	return func(c *extraConfig) {
+		c.mode = mode
+	}
+}
+
+// WithTemplateExtensions mutates Renderer configuration by replacing the file extensions
+// recognised as templates and trimmed from the output file names in the directory mode,
+// the extensions can have multiple parts, e.g. '.yaml.j2', the leading dot is optional
+func WithTemplateExtensions(extensions ...string) Option {
+	return func(c *extraConfig) {
+		c.templateExtensions = nil
+		for _, ext := range extensions {
+			if len(ext) == 0 {
+				continue
+			}
+			if !strings.HasPrefix(ext, ".") {
+				ext = "." + ext
+			}
+			c.templateExtensions = append(c.templateExtensions, ext)
+		}
+	}
+}
+
+// WithJobs mutates Renderer configuration by setting the number of files rendered concurrently
+// in the directory mode, zero or less means the number of CPUs
+func WithJobs(jobs int) Option {
+	return func(c *extraConfig) {
+		c.jobs = jobs
+	}
+}
+
+// DefaultTemplateExtensions returns the file extensions recognised as templates if none are set
+func DefaultTemplateExtensions() []string {
+	return []string{".tpl", ".tmpl"}
+}
+
+// FileError is an error of a single file in the directory mode
+type FileError struct {
+	Path string
+	Err  error
+}
+
+func (e *FileError) Error() string {
+	return fmt.Sprintf("'%s': %s", e.Path, e.Err)
+}
+
+// Cause returns the underlying error, see also errors.Cause
+func (e *FileError) Cause() error {
+	return e.Err
+}
+
+// FileErrors aggregates the per file errors in the directory mode
+type FileErrors []*FileError
+
+func (e FileErrors) Error() string {
+	messages := make([]string, len(e))
+	for i, err := range e {
+		messages[i] = err.Error()
+	}
+	return fmt.Sprintf("can't process %d file(s):\n\t%s", len(e), strings.Join(messages, "\n\t"))
+}
+
+// dirTask is a single file to be processed in the directory mode
+type dirTask struct {
+	inputPath  string
+	outputPath string
+	copy       bool
+	mode       os.FileMode   // the file mode of the output set in the front matter, zero if none
+	template   *templateFile // read and parsed by dirTasks with the front matter enabled, nil if not read yet
+	skip       bool          // the front matter skips the file, only in the watch mode, see also fileTasks
+}
+
+// dirResult is the outcome of a single dirTask
+type dirResult struct {
+	content string
+	err     error
+}
+
+// DirRender is used to render files by directory, see also FileRender, WithCheck, WithAtomic and WithFrontMatter.
+// The templates are rendered concurrently (see WithJobs), but the outputs are written
+// and logged in the order of the input paths, the errors are aggregated per file, see also FileErrors
+func (r *renderer) DirRender(inputDir, outputDir string) error {
+	logrus.Infof("Directory mode selected: '%s' -> '%s'", inputDir, outputDir)
+
+	var previous []manifestEntry
+	var err error
+	if r.extra.prune {
+		previous, err = readManifest(filepath.Join(outputDir, ManifestFileName))
+		if err != nil {
+			return err
+		}
+	}
+
+	tasks, err := r.dirTasks(inputDir, outputDir, previous)
+	if err != nil {
+		return err
+	}
+
+	var stagingDir string
+	if r.extra.atomic && !r.extra.check {
+		stagingDir, err = createStagingDir(outputDir)
+		if err != nil {
+			return err
+		}
+		defer removeStagingDir(stagingDir)
+	}
+
+	var fileErrors FileErrors
+	var drifted []string
+	results := r.renderTasks(tasks)
+	for i, task := range tasks {
+		result := <-results[i]
+		if result.err == nil && r.extra.check {
+			var drift bool
+			drift, result.err = task.check(result.content, r.extra.diff)
+			if drift {
+				drifted = append(drifted, task.outputPath)
+			}
+		} else if result.err == nil {
+			var staged dirTask
+			staged, result.err = task.staged(outputDir, stagingDir)
+			if result.err == nil {
+				result.err = staged.write(result.content)
+			}
+		}
+		if result.err != nil {
+			fileErrors = append(fileErrors, &FileError{Path: task.inputPath, Err: result.err})
+		}
+	}
+	if len(fileErrors) > 0 {
+		if len(stagingDir) > 0 {
+			logrus.Infof("Discarding the staged files, '%s' was left untouched", outputDir)
+		}
+		return fileErrors
+	}
+	if len(stagingDir) > 0 {
+		err = commitStaged(outputDir, stagingDir, tasks)
+		if err != nil {
+			return err
+		}
+	}
+
+	if r.extra.prune {
+		stale, err := r.prune(outputDir, previous, tasks)
+		if err != nil {
+			return err
+		}
+		drifted = append(drifted, stale...)
+	}
+	if len(drifted) > 0 {
+		return &DriftError{Paths: drifted}
+	}
+
+	return nil
+}
+
+// plannedOutput is the input planned for an output path by dirTasks
+type plannedOutput struct {
+	inputPath string
+	index     int  // of the task
+	template  bool // the input has a template extension
+}
+
+// dirTasks scans the input directory and plans what to do with each of the files,
+// the inputs rendered in place that are recorded in the previous manifest are skipped, see also WithPrune
+func (r *renderer) dirTasks(inputDir, outputDir string, previous []manifestEntry) ([]dirTask, error) {
+	mode := r.extra.mode
+	if len(mode) == 0 {
+		mode = DefaultDirMode
+	}
+	if !isDirMode(mode) {
+		return nil, errors.Errorf("unexpected directory mode: '%s', mode must be in: '%s'", mode, DirModes())
+	}
+
+	extensions := r.extra.templateExtensions
+	if len(extensions) == 0 {
+		extensions = DefaultTemplateExtensions()
+	}
+
+	fileEntries, err := dirTree(inputDir, r.extra.ignorePatterns)
+	if err != nil {
+		return nil, errors.Wrapf(err, "can't scan the directory tree: '%s'", inputDir)
+	}
+
+	recorded := make(map[string]bool, len(previous)) // the outputs of the previous run, by the relative paths
+	for _, entry := range previous {
+		recorded[entry.path] = true
+	}
+
+	var tasks []dirTask
+	var fileErrors FileErrors
+	outputs := make(map[string]plannedOutput) // by the output path, to detect conflicts
+	for _, file := range fileEntries {
+		logrus.Debugf("Processing '%s'", path.Join(file.path, file.name))
+
+		_, isTemplate := templateExtension(file, extensions)
+		if !isTemplate && mode == SkipNonTemplatesMode {
+			logrus.Debugf("Skipping a non-template file '%s'", path.Join(file.path, file.name))
+			continue
+		}
+
+		target := trimExtension(file, extensions)
+
+		rel, err := filepath.Rel(inputDir, file.path)
+		if err != nil {
+			return nil, errors.Wrapf(err, "can't get a relative path for: '%s'", file.path)
+		}
+
+		inputPath := path.Join(file.path, file.name)
+		isCopy := !isTemplate && mode == CopyNonTemplatesMode
+		var template *templateFile
+		var front *frontMatter
+		if !isCopy && r.extra.frontMatter {
+			t, err := r.readTemplate(inputPath)
+			if err != nil {
+				fileErrors = append(fileErrors, &FileError{Path: inputPath, Err: err})
+				continue
+			}
+			template, front = &t, t.front
+		}
+		if front != nil && front.skip {
+			logrus.Debugf("Skipping '%s', as set in the front matter", inputPath)
+			continue
+		}
+
+		outputRel, ok, err := r.renderOutputPath(path.Join(filepath.ToSlash(rel), target.name))
+		if err != nil {
+			fileErrors = append(fileErrors, &FileError{Path: inputPath, Err: err})
+			continue
+		}
+		if !ok {
+			logrus.Debugf("Skipping '%s', the output path was rendered empty", inputPath)
+			continue
+		}
+		var outputMode os.FileMode
+		if front != nil {
+			if len(front.output) > 0 {
+				outputRel = path.Join(path.Dir(outputRel), front.output)
+			}
+			outputMode = front.mode
+		}
+
+		outputPath := path.Join(outputDir, outputRel)
+		if outputPath == inputPath && recorded[outputRel] {
+			// rendering in place, the input is a stale output of the previous run rather than a source
+			logrus.Debugf("Skipping '%s', it is an output recorded in the manifest", inputPath)
+			continue
+		}
+		task := dirTask{
+			inputPath:  inputPath,
+			outputPath: outputPath,
+			copy:       isCopy,
+			mode:       outputMode,
+			template:   template,
+		}
+		if other, ok := outputs[outputPath]; ok {
+			// a template wins over a non-template, e.g. its own output when rendering in place
+			switch {
+			case isTemplate && !other.template:
+				logrus.Debugf("Skipping '%s', the output path '%s' is the same as of the template '%s'", other.inputPath, outputPath, inputPath)
+				tasks[other.index] = task
+				outputs[outputPath] = plannedOutput{inputPath: inputPath, index: other.index, template: true}
+			case !isTemplate && other.template:
+				logrus.Debugf("Skipping '%s', the output path '%s' is the same as of the template '%s'", inputPath, outputPath, other.inputPath)
+			default:
+				fileErrors = append(fileErrors, &FileError{
+					Path: inputPath,
+					Err:  errors.Errorf("conflict, the output path '%s' is the same as of '%s'", outputPath, other.inputPath),
+				})
+			}
+			continue
+		}
+		outputs[outputPath] = plannedOutput{inputPath: inputPath, index: len(tasks), template: isTemplate}
+		tasks = append(tasks, task)
+	}
+	if len(fileErrors) > 0 {
+		return nil, fileErrors
+	}
+	return tasks, nil
+}
+
+// renderOutputPath renders the templates in the segments of the slash separated relative output path
+// with the same parameters as the files, returns false if any of the segments is rendered empty
+func (r *renderer) renderOutputPath(rel string) (string, bool, error) {
+	leftDelim := r.Configuration().LeftDelim
+	segments := strings.Split(rel, "/")
+	for i, segment := range segments {
+		if !strings.Contains(segment, leftDelim) {
+			continue
+		}
+		rendered, err := r.NamedRender(rel, segment)
+		if err != nil {
+			return "", false, errors.Wrapf(err, "can't render the output path: '%s'", rel)
+		}
+		rendered = strings.TrimSpace(rendered)
+		if len(rendered) == 0 {
+			return "", false, nil
+		}
+		if rendered == "." || rendered == ".." || strings.ContainsAny(rendered, `/\`) {
+			return "", false, errors.Errorf("unexpected path segment '%s' rendered from '%s'", rendered, segment)
+		}
+		segments[i] = rendered
+	}
+	return strings.Join(segments, "/"), true, nil
+}
+
+// renderTasks renders the templates with a pool of workers, each file with its own clone of the renderer,
+// the returned channels are in the order of the tasks and each receives exactly one result
+func (r *renderer) renderTasks(tasks []dirTask) []chan dirResult {
+	results := make([]chan dirResult, len(tasks))
+	for i := range results {
+		results[i] = make(chan dirResult, 1)
+	}
+
+	jobs := r.extra.jobs
+	if jobs <= 0 {
+		jobs = runtime.NumCPU()
+	}
+	if jobs > len(tasks) {
+		jobs = len(tasks)
+	}
+	logrus.Debugf("Rendering %d file(s) with %d job(s)", len(tasks), jobs)
+
+	indexes := make(chan int)
+	go func() {
+		for i := range tasks {
+			indexes <- i
+		}
+		close(indexes)
+	}()
+
+	for j := 0; j < jobs; j++ {
+		go func() {
+			for i := range indexes {
+				if tasks[i].copy {
+					results[i] <- dirResult{}
+					continue
+				}
+				// each file gets its own clone, so a template modifying the parameters doesn't affect the others
+				var result dirResult
+				if t := tasks[i].template; t != nil {
+					result.content, result.err = r.clone().renderTemplate(*t)
+				} else {
+					result.content, result.err = r.clone().renderFile(tasks[i].inputPath)
+				}
+				results[i] <- result
+			}
+		}()
+	}
+	return results
+}
+
+// check compares the rendered content or the copied file with the existing output file
+func (task dirTask) check(content string, diff io.Writer) (bool, error) {
+	expected := []byte(content)
+	if task.copy {
+		var err error
+		expected, err = ioutil.ReadFile(task.inputPath)
+		if err != nil {
+			return false, errors.Wrapf(err, "can't read the file: '%s'", task.inputPath)
+		}
+	}
+	return checkOutput(task.outputPath, expected, diff)
+}
+
+// write saves the rendered content or copies the file, creates the target directory if necessary
+func (task dirTask) write(content string) error {
+	targetDir := filepath.Dir(task.outputPath)
+	_, err := os.Stat(targetDir)
+	if os.IsNotExist(err) {
+		err := os.MkdirAll(targetDir, os.ModePerm)
+		if err != nil {
+			return errors.Wrapf(err, "can't create the target directory: '%s'", targetDir)
+		}
+		logrus.Infof("Target directory was created: '%s'", targetDir)
+	} else if err != nil {
+		return errors.Wrapf(err, "can't get file information for '%s'", targetDir)
+	}
+
+	if task.copy {
+		return copyFile(task.inputPath, task.outputPath)
+	}
+
+	logrus.Infof("Rendering '%s' -> '%s'", task.inputPath, task.outputPath)
+	logrus.Debugf("%s: \n%s", task.outputPath, content)
+	return writeOutput(task.outputPath, []byte(content), task.mode)
+}
+
+// TODO move to files package
+type dirEntry struct {
+	path      string
+	name      string
+	extension string
+}
+
+// TODO move to files package
+func dirTree(input string, ignorePatterns []string) (entries []dirEntry, err error) {
+	rules := make(map[string]ignoreRules)
+	err = filepath.Walk(input, func(path string, info os.FileInfo, dirErr error) error {
+		if dirErr != nil {
+			logrus.Errorf("error '%v' on path '%s'", dirErr, path)
+			return dirErr
+		}
+
+		logrus.Debugf("Discovered path: '%s'", path)
+
+		rel, err := filepath.Rel(input, path)
+		if err != nil {
+			return errors.Wrapf(err, "can't get a relative path for: '%s'", path)
+		}
+		rel = filepath.ToSlash(rel)
+
+		if rel == "." && info.IsDir() {
+			rules[rel], err = ignoreRules{}.withPatterns("", ignorePatterns).withFile(input, "")
+			return err
+		}
+
+		parentRules := rules[filepath.ToSlash(filepath.Dir(rel))]
+		if info.Name() == IgnoreFileName || info.Name() == ManifestFileName || parentRules.ignored(rel, info.IsDir()) {
+			logrus.Debugf("Ignored path: '%s'", path)
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		if info.IsDir() {
+			rules[rel], err = parentRules.withFile(input, rel)
+			return err
+		}
+
+		logrus.Tracef("  dir  : '%s'", filepath.Dir(path))
+		logrus.Tracef("  name : '%s'", info.Name())
+		logrus.Tracef("  ext  : '%s'", filepath.Ext(path))
+
+		entry := dirEntry{
+			path:      filepath.Dir(path),
+			name:      info.Name(),
+			extension: filepath.Ext(path),
+		}
+		entries = append(entries, entry)
+		return nil
+	})
+	if err != nil {
+		return entries, errors.Wrapf(err, "can't walk the directory tree '%s'", input)
+	}
+
+	return entries, nil
+}
+
+func isDirMode(mode DirMode) bool {
+	for _, m := range DirModes() {
+		if mode == m {
+			return true
+		}
+	}
+	return false
+}
+
+// templateExtension returns the longest of the extensions the file name ends with
+func templateExtension(file dirEntry, extensions []string) (extension string, ok bool) {
+	for _, ext := range extensions {
+		if len(ext) > len(extension) && len(file.name) > len(ext) && strings.HasSuffix(file.name, ext) {
+			extension = ext
+			ok = true
+		}
+	}
+	return
+}
+
+// copyFile copies the file content byte-for-byte and keeps the file mode bits
+func copyFile(inputPath, outputPath string) error {
+	logrus.Infof("Copying '%s' -> '%s'", inputPath, outputPath)
+
+	info, err := os.Stat(inputPath)
+	if err != nil {
+		return errors.Wrapf(err, "can't get file information for '%s'", inputPath)
+	}
+	if outputInfo, err := os.Stat(outputPath); err == nil && os.SameFile(info, outputInfo) {
+		logrus.Debugf("Skipping a copy of '%s' onto itself", inputPath)
+		return nil
+	}
+	in, err := os.Open(inputPath)
+	if err != nil {
+		return errors.Wrapf(err, "can't open the file: '%s'", inputPath)
+	}
+	defer func() { _ = in.Close() }()
+
+	out, err := os.OpenFile(outputPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, info.Mode().Perm())
+	if err != nil {
+		return errors.Wrapf(err, "can't create the file: '%s'", outputPath)
+	}
+	_, err = io.Copy(out, in)
+	if err != nil {
+		_ = out.Close()
+		return errors.Wrapf(err, "can't copy the file: '%s'", inputPath)
+	}
+	err = out.Close()
+	if err != nil {
+		return errors.Wrapf(err, "can't close the file: '%s'", outputPath)
+	}
+	// the mode of an existing file is not changed by os.OpenFile
+	return os.Chmod(outputPath, info.Mode().Perm())
+}
+
+func trimExtension(file dirEntry, extensions []string) (new dirEntry) {
+	new = file
+	if ext, ok := templateExtension(file, extensions); ok {
+		new.name = strings.TrimSuffix(file.name, ext)
+		new.extension = filepath.Ext(new.name)
+	}
+	return
+}
@@ -0,0 +1,380 @@
+package renderer
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRenderer_DirRender_Modes(t *testing.T) {
+	input := map[string]string{
+		"a.yaml.tmpl": "{{ .value }}",
+		"b.tpl":       "{{ .value }}",
+		"c.txt":       "{{ .value }}",
+		"sub/run.sh":  "#!/bin/sh\necho {{ .value }}",
+	}
+	params := map[string]interface{}{"value": "some"}
+
+	Run(t, Test{
+		name: "dir render with the default mode",
+		f: func(tt Test) {
+			inputDir, outputDir := tempDirs(t)
+			defer cleanup(inputDir, outputDir)
+			writeTree(t, inputDir, input)
+			assert.NoError(t, os.Chmod(filepath.Join(inputDir, "sub", "run.sh"), 0755))
+
+			err := New(WithParameters(params)).DirRender(inputDir, outputDir)
+
+			assert.NoError(t, err, tt.name)
+			assert.Equal(t, map[string]string{
+				"a.yaml":     "some",
+				"b":          "some",
+				"c.txt":      "{{ .value }}",
+				"sub/run.sh": "#!/bin/sh\necho {{ .value }}",
+			}, readTree(t, outputDir))
+			info, err := os.Stat(filepath.Join(outputDir, "sub", "run.sh"))
+			assert.NoError(t, err)
+			assert.Equal(t, os.FileMode(0755), info.Mode().Perm())
+			assert.Equal(t, 0, CountProblems(tt.logHook))
+		},
+	})
+
+	Run(t, Test{
+		name: "dir render with the render all mode",
+		f: func(tt Test) {
+			inputDir, outputDir := tempDirs(t)
+			defer cleanup(inputDir, outputDir)
+			writeTree(t, inputDir, input)
+
+			err := NewWithOptions([]Option{WithDirMode(RenderAllMode)}, WithParameters(params)).DirRender(inputDir, outputDir)
+
+			assert.NoError(t, err, tt.name)
+			assert.Equal(t, map[string]string{
+				"a.yaml":     "some",
+				"b":          "some",
+				"c.txt":      "some",
+				"sub/run.sh": "#!/bin/sh\necho some",
+			}, readTree(t, outputDir))
+			assert.Equal(t, 0, CountProblems(tt.logHook))
+		},
+	})
+
+	Run(t, Test{
+		name: "dir render with the skip mode",
+		f: func(tt Test) {
+			inputDir, outputDir := tempDirs(t)
+			defer cleanup(inputDir, outputDir)
+			writeTree(t, inputDir, input)
+
+			err := NewWithOptions([]Option{WithDirMode(SkipNonTemplatesMode)}, WithParameters(params)).DirRender(inputDir, outputDir)
+
+			assert.NoError(t, err, tt.name)
+			assert.Equal(t, map[string]string{
+				"a.yaml": "some",
+				"b":      "some",
+			}, readTree(t, outputDir))
+			assert.Equal(t, 0, CountProblems(tt.logHook))
+		},
+	})
+
+	Run(t, Test{
+		name: "dir render in place",
+		f: func(tt Test) {
+			inputDir, outputDir := tempDirs(t)
+			defer cleanup(inputDir, outputDir)
+			writeTree(t, inputDir, input)
+
+			err := New(WithParameters(params)).DirRender(inputDir, inputDir)
+
+			assert.NoError(t, err, tt.name)
+			assert.Equal(t, "{{ .value }}", readTree(t, inputDir)["c.txt"])
+			assert.Equal(t, 0, CountProblems(tt.logHook))
+		},
+	})
+
+	for _, mode := range []DirMode{CopyNonTemplatesMode, RenderAllMode} {
+		Run(t, Test{
+			name: "dir render in place twice with the " + string(mode) + " mode",
+			f: func(tt Test) {
+				inputDir, outputDir := tempDirs(t)
+				defer cleanup(inputDir, outputDir)
+				writeTree(t, inputDir, input)
+
+				r := NewWithOptions([]Option{WithDirMode(mode)}, WithParameters(params))
+				err := r.DirRender(inputDir, inputDir)
+				assert.NoError(t, err, tt.name)
+				first := readTree(t, inputDir)
+
+				err = r.DirRender(inputDir, inputDir)
+				assert.NoError(t, err, tt.name)
+				assert.Equal(t, first, readTree(t, inputDir))
+				assert.Equal(t, "some", first["a.yaml"])
+				assert.Equal(t, "some", first["b"])
+				assert.Equal(t, 0, CountProblems(tt.logHook))
+			},
+		})
+	}
+
+	Run(t, Test{
+		name: "dir render with an unexpected mode",
+		f: func(tt Test) {
+			inputDir, outputDir := tempDirs(t)
+			defer cleanup(inputDir, outputDir)
+
+			err := NewWithOptions([]Option{WithDirMode("wrong")}).DirRender(inputDir, outputDir)
+
+			assert.EqualError(t, err, "unexpected directory mode: 'wrong', mode must be in: '[all copy skip]'")
+		},
+	})
+}
+
+func TestRenderer_DirRender_TemplateExtensions(t *testing.T) {
+	Run(t, Test{
+		name: "dir render with custom template extensions",
+		f: func(tt Test) {
+			inputDir, outputDir := tempDirs(t)
+			defer cleanup(inputDir, outputDir)
+			writeTree(t, inputDir, map[string]string{
+				"a.yaml.gotmpl": "{{ .value }}",
+				"b.yaml.j2":     "{{ .value }}",
+				"c.yaml.tmpl":   "{{ .value }}",
+				".gotmpl":       "{{ .value }}",
+			})
+
+			err := NewWithOptions(
+				[]Option{WithTemplateExtensions(".gotmpl", "yaml.j2")},
+				WithParameters(map[string]interface{}{"value": "some"}),
+			).DirRender(inputDir, outputDir)
+
+			assert.NoError(t, err, tt.name)
+			assert.Equal(t, map[string]string{
+				"a.yaml":      "some",
+				"b":           "some",
+				"c.yaml.tmpl": "{{ .value }}",
+				".gotmpl":     "{{ .value }}",
+			}, readTree(t, outputDir))
+			assert.Equal(t, 0, CountProblems(tt.logHook))
+		},
+	})
+}
+
+func TestRenderer_DirRender_Jobs(t *testing.T) {
+	params := map[string]interface{}{
+		"inner": "{{ .value }}",
+		"value": "some",
+		"override": map[string]interface{}{
+			"value": "other",
+		},
+	}
+	input := make(map[string]string)
+	expected := make(map[string]string)
+	for i := 0; i < 50; i++ {
+		input[fmt.Sprintf("dir%d/file%02d.yaml.tmpl", i%5, i)] = `{{ .value }} {{ .inner | render .override }} {{ .inner | render }}`
+		expected[fmt.Sprintf("dir%d/file%02d.yaml", i%5, i)] = "some other some"
+	}
+
+	Run(t, Test{
+		name: "dir render with many jobs",
+		f: func(tt Test) {
+			inputDir, outputDir := tempDirs(t)
+			defer cleanup(inputDir, outputDir)
+			writeTree(t, inputDir, input)
+
+			err := NewWithOptions(
+				[]Option{WithJobs(8)},
+				WithParameters(params),
+				WithSprigFunctions(),
+			).DirRender(inputDir, outputDir)
+
+			assert.NoError(t, err, tt.name)
+			assert.Equal(t, expected, readTree(t, outputDir))
+			assert.Equal(t, 0, CountProblems(tt.logHook))
+
+			var rendered []string
+			for _, entry := range tt.logHook.AllEntries() {
+				if strings.HasPrefix(entry.Message, "Rendering '") {
+					rendered = append(rendered, entry.Message)
+				}
+			}
+			assert.Len(t, rendered, len(input))
+			assert.True(t, sort.StringsAreSorted(rendered), "rendering should be logged in order")
+		},
+	})
+
+	Run(t, Test{
+		name: "dir render with errors aggregated per file",
+		f: func(tt Test) {
+			inputDir, outputDir := tempDirs(t)
+			defer cleanup(inputDir, outputDir)
+			writeTree(t, inputDir, map[string]string{
+				"a.tmpl": "{{ .missing }}",
+				"b.tmpl": "{{ .value }}",
+				"c.tmpl": "{{ broken",
+			})
+
+			err := NewWithOptions(
+				[]Option{WithJobs(0)},
+				WithParameters(params),
+			).DirRender(inputDir, outputDir)
+
+			assert.Error(t, err, tt.name)
+			fileErrors, ok := err.(FileErrors)
+			assert.True(t, ok, "expected FileErrors, got: %T", err)
+			assert.Len(t, fileErrors, 2)
+			assert.Equal(t, filepath.Join(inputDir, "a.tmpl"), fileErrors[0].Path)
+			assert.Equal(t, filepath.Join(inputDir, "c.tmpl"), fileErrors[1].Path)
+			assert.Equal(t, map[string]string{"b": "some"}, readTree(t, outputDir))
+		},
+	})
+}
+
+// run with -race to detect the templates sharing the parameters
+func TestRenderer_DirRender_JobsIsolation(t *testing.T) {
+	input := make(map[string]string)
+	expected := make(map[string]string)
+	for i := 0; i < 100; i++ {
+		input[fmt.Sprintf("file%03d.tmpl", i)] = fmt.Sprintf(
+			`{{ hasKey $ "k" }} {{ $_ := set $ "k" %d }}{{ $_ := set .nested "k" %d }}{{ .k }} {{ .nested.k }}{{ $_ := unset $ "value" }}`, i, i)
+		expected[fmt.Sprintf("file%03d", i)] = fmt.Sprintf("false %d %d", i, i)
+	}
+
+	Run(t, Test{
+		name: "dir render with the templates modifying the parameters",
+		f: func(tt Test) {
+			inputDir, outputDir := tempDirs(t)
+			defer cleanup(inputDir, outputDir)
+			writeTree(t, inputDir, input)
+			params := map[string]interface{}{
+				"value":  "some",
+				"nested": map[string]interface{}{},
+			}
+
+			err := NewWithOptions(
+				[]Option{WithJobs(8)},
+				WithParameters(params),
+				WithSprigFunctions(),
+			).DirRender(inputDir, outputDir)
+
+			assert.NoError(t, err, tt.name)
+			assert.Equal(t, expected, readTree(t, outputDir))
+			assert.Equal(t, map[string]interface{}{"value": "some", "nested": map[string]interface{}{}}, params)
+		},
+	})
+}
+
+func TestRenderer_DirRender_TemplatedPaths(t *testing.T) {
+	params := map[string]interface{}{
+		"app_name": "render",
+		"kind":     "deployment",
+		"enabled":  false,
+	}
+
+	Run(t, Test{
+		name: "dir render with templated paths",
+		f: func(tt Test) {
+			inputDir, outputDir := tempDirs(t)
+			defer cleanup(inputDir, outputDir)
+			writeTree(t, inputDir, map[string]string{
+				"{{ .app_name }}/{{ .kind }}.yaml.tmpl":                 "name: {{ .app_name }}",
+				"{{ .app_name }}/static.txt":                            "copied",
+				"{{ if .enabled }}optional{{ end }}/a.yaml.tmpl":        "{{ broken",
+				"{{ .app_name }}/{{ if .enabled }}b.yaml{{ end }}.tmpl": "{{ broken",
+			})
+
+			err := New(WithParameters(params)).DirRender(inputDir, outputDir)
+
+			assert.NoError(t, err, tt.name)
+			assert.Equal(t, map[string]string{
+				"render/deployment.yaml": "name: render",
+				"render/static.txt":      "copied",
+			}, readTree(t, outputDir))
+			assert.Equal(t, 0, CountProblems(tt.logHook))
+		},
+	})
+
+	Run(t, Test{
+		name: "dir render with invalid templated paths",
+		f: func(tt Test) {
+			inputDir, outputDir := tempDirs(t)
+			defer cleanup(inputDir, outputDir)
+			writeTree(t, inputDir, map[string]string{
+				"{{ .missing }}.tmpl": "some",
+				"{{ \"..\" }}.tmpl":   "some",
+				"a.tmpl":              "some",
+				"{{ \"a\" }}.tmpl":    "some",
+			})
+
+			err := New(WithParameters(params)).DirRender(inputDir, outputDir)
+
+			assert.Error(t, err, tt.name)
+			fileErrors, ok := err.(FileErrors)
+			assert.True(t, ok, "expected FileErrors, got: %T", err)
+			assert.Len(t, fileErrors, 3)
+			assert.Equal(t, map[string]string{}, readTree(t, outputDir))
+		},
+	})
+}
+
+func tempDirs(t *testing.T) (inputDir, outputDir string) {
+	inputDir, err := ioutil.TempDir("", "render-in")
+	if err != nil {
+		t.Fatal(err)
+	}
+	outputDir, err = ioutil.TempDir("", "render-out")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return inputDir, outputDir
+}
+
+func cleanup(dirs ...string) {
+	for _, dir := range dirs {
+		_ = os.RemoveAll(dir)
+	}
+}
+
+// writeTree creates the files with the given slash separated relative paths and contents
+func writeTree(t *testing.T, dir string, tree map[string]string) {
+	for name, content := range tree {
+		p := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(p, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// readTree returns all the files in the directory with slash separated relative paths, if the directory exists
+func readTree(t *testing.T, dir string) map[string]string {
+	tree := make(map[string]string)
+	err := filepath.Walk(dir, func(p string, info os.FileInfo, err error) error {
+		if os.IsNotExist(err) && p == dir {
+			return nil
+		}
+		if err != nil || info.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		content, err := ioutil.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		tree[filepath.ToSlash(rel)] = string(content)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tree
+}
@@ -0,0 +1,128 @@
+package renderer
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/VirtusLab/go-extended/pkg/renderer/config"
+	"github.com/VirtusLab/render/renderer/parameters"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// ForeachItemKey is the parameter key of the current element in ForeachRender
+	ForeachItemKey = "item"
+	// ForeachIndexKey is the parameter key of the index of the current element in ForeachRender
+	ForeachIndexKey = "index"
+)
+
+// ForeachRender renders the template once per element of the list parameter with the given key, e.g. 'app.services[0].ports',
+// the element and its index are available as the ForeachItemKey and ForeachIndexKey parameters, overriding (with a warning)
+// the existing ones, the output path is a template rendered with the same parameters, an element with an empty output path is skipped,
+// the template is read once, stdin is used if the input path is empty, see also FileRender and WithFrontMatter
+func (r *renderer) ForeachRender(inputPath, listKey, outputPathTemplate string) error {
+	if len(outputPathTemplate) == 0 {
+		return errors.New("the output path template is required to render each element")
+	}
+	items, err := listParameter(r.Configuration().Parameters, listKey)
+	if err != nil {
+		return err
+	}
+	for _, key := range []string{ForeachItemKey, ForeachIndexKey} {
+		if _, ok := r.Configuration().Parameters[key]; ok {
+			logrus.Warnf("The parameter '%s' is overridden by the current element in the foreach mode", key)
+		}
+	}
+
+	t, err := r.readTemplate(inputPath)
+	if err != nil {
+		return err
+	}
+	if t.front != nil && t.front.skip {
+		logrus.Infof("Skipping '%s', as set in the front matter", t.name)
+		return nil
+	}
+	logrus.Infof("Rendering '%s' for each of the %d element(s) of '%s'", t.name, len(items), listKey)
+
+	var fileErrors FileErrors
+	var drifted []string
+	inputs := make(map[string]int) // by the output path, to detect conflicts
+	for i, item := range items {
+		name := fmt.Sprintf("%s[%d]", listKey, i)
+		clone := r.clone(withParameter(ForeachItemKey, item), withParameter(ForeachIndexKey, i))
+
+		outputPath, err := clone.NamedRender(name, outputPathTemplate)
+		if err != nil {
+			fileErrors = append(fileErrors, &FileError{Path: name, Err: errors.Wrap(err, "can't render the output path")})
+			continue
+		}
+		outputPath = strings.TrimSpace(outputPath)
+		if len(outputPath) == 0 {
+			logrus.Debugf("Skipping '%s', the output path was rendered empty", name)
+			continue
+		}
+		if other, ok := inputs[outputPath]; ok {
+			fileErrors = append(fileErrors, &FileError{
+				Path: name,
+				Err:  errors.Errorf("conflict, the output path '%s' is the same as of '%s[%d]'", outputPath, listKey, other),
+			})
+			continue
+		}
+		inputs[outputPath] = i
+
+		logrus.Infof("Rendering '%s' -> '%s'", name, outputPath)
+		result, err := clone.renderTemplate(t)
+		if err == nil {
+			err = clone.output(outputPath, result, t.mode())
+		}
+		switch e := err.(type) {
+		case nil:
+		case *DriftError:
+			drifted = append(drifted, e.Paths...)
+		default:
+			fileErrors = append(fileErrors, &FileError{Path: name, Err: err})
+		}
+	}
+	if len(fileErrors) > 0 {
+		return fileErrors
+	}
+	if len(drifted) > 0 {
+		return &DriftError{Paths: drifted}
+	}
+	return nil
+}
+
+// listParameter returns the elements of the list parameter with the given key, see also parameters.Parameters.Lookup
+func listParameter(params parameters.Parameters, key string) ([]interface{}, error) {
+	value, ok, err := params.Lookup(key)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, errors.Errorf("can't find the list parameter: '%s'", key)
+	}
+
+	list := reflect.ValueOf(value)
+	if list.Kind() != reflect.Slice && list.Kind() != reflect.Array {
+		return nil, errors.Errorf("expected the parameter '%s' to be a list, got: '%T'", key, value)
+	}
+	items := make([]interface{}, list.Len())
+	for i := range items {
+		items[i] = list.Index(i).Interface()
+	}
+	return items, nil
+}
+
+// withParameter mutates Renderer configuration by setting (overriding) a single template parameter
+func withParameter(key string, value interface{}) func(*config.Config) {
+	return func(c *config.Config) {
+		params := make(map[string]interface{}, len(c.Parameters)+1)
+		for k, v := range c.Parameters {
+			params[k] = v
+		}
+		params[key] = value
+		c.Parameters = params
+	}
+}
@@ -0,0 +1,104 @@
+package renderer
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRenderer_ForeachRender(t *testing.T) {
+	params := map[string]interface{}{
+		"namespace": "default",
+		"app": map[string]interface{}{
+			"services": []interface{}{
+				map[string]interface{}{"name": "api", "port": 8080},
+				map[string]interface{}{"name": "web", "port": 80},
+				map[string]interface{}{"name": "", "port": 0},
+			},
+		},
+	}
+
+	Run(t, Test{
+		name: "foreach render",
+		f: func(tt Test) {
+			inputDir, outputDir := tempDirs(t)
+			defer cleanup(inputDir, outputDir)
+			writeTree(t, inputDir, map[string]string{
+				"service.yaml.tmpl": "{{ .index }}: {{ .item.name }}.{{ .namespace }}:{{ .item.port }}",
+			})
+
+			err := New(WithParameters(params)).ForeachRender(
+				filepath.Join(inputDir, "service.yaml.tmpl"),
+				"app.services",
+				"{{ with .item.name }}"+outputDir+"/{{ . }}/service.yaml{{ end }}",
+			)
+
+			assert.NoError(t, err, tt.name)
+			assert.Equal(t, map[string]string{
+				"api/service.yaml": "0: api.default:8080",
+				"web/service.yaml": "1: web.default:80",
+			}, readTree(t, outputDir))
+			assert.Equal(t, 0, CountProblems(tt.logHook))
+		},
+	})
+
+	Run(t, Test{
+		name: "foreach render with conflicting output paths",
+		f: func(tt Test) {
+			inputDir, outputDir := tempDirs(t)
+			defer cleanup(inputDir, outputDir)
+			writeTree(t, inputDir, map[string]string{"a.tmpl": "{{ .item.name }}"})
+
+			err := New(WithParameters(params)).ForeachRender(
+				filepath.Join(inputDir, "a.tmpl"),
+				"app.services",
+				outputDir+"/same.yaml",
+			)
+
+			assert.Error(t, err, tt.name)
+			fileErrors, ok := err.(FileErrors)
+			assert.True(t, ok, "expected FileErrors, got: %T", err)
+			assert.Len(t, fileErrors, 2)
+			assert.Equal(t, "app.services[1]", fileErrors[0].Path)
+		},
+	})
+
+	Run(t, Test{
+		name: "foreach render with a missing list",
+		f: func(tt Test) {
+			err := New(WithParameters(params)).ForeachRender("", "app.missing", "out.yaml")
+			assert.EqualError(t, err, "can't find the list parameter: 'app.missing'", tt.name)
+		},
+	})
+
+	Run(t, Test{
+		name: "foreach render with a parameter that is not a list",
+		f: func(tt Test) {
+			err := New(WithParameters(params)).ForeachRender("", "namespace", "out.yaml")
+			assert.EqualError(t, err, "expected the parameter 'namespace' to be a list, got: 'string'", tt.name)
+		},
+	})
+	Run(t, Test{
+		name: "foreach render with an escaped key and an existing item parameter",
+		f: func(tt Test) {
+			inputDir, outputDir := tempDirs(t)
+			defer cleanup(inputDir, outputDir)
+			writeTree(t, inputDir, map[string]string{"a.tmpl": "{{ .item }}"})
+			params := map[string]interface{}{
+				"item":        "existing",
+				"app.example": map[string]interface{}{"names": []interface{}{"a", "b"}},
+			}
+
+			err := New(WithParameters(params)).ForeachRender(
+				filepath.Join(inputDir, "a.tmpl"),
+				`app\.example.names`,
+				outputDir+"/{{ .item }}",
+			)
+
+			assert.NoError(t, err, tt.name)
+			assert.Equal(t, map[string]string{"a": "a", "b": "b"}, readTree(t, outputDir))
+			assert.Equal(t, 1, CountProblems(tt.logHook))
+		},
+	})
+}
@@ -0,0 +1,254 @@
+package renderer
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/VirtusLab/go-extended/pkg/files"
+	"github.com/VirtusLab/go-extended/pkg/renderer/config"
+	"github.com/VirtusLab/render/renderer/parameters"
+	"github.com/ghodss/yaml"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// FrontMatterDelim is the line opening and closing the front matter, see also WithFrontMatter
+	FrontMatterDelim = "---"
+	// FrontMatterOptionsKey is the reserved front matter key with the per-file options:
+	// 'output' (the output path relative to the output directory of the template, in the directory mode only),
+	// 'mode' (the file mode of the output, e.g. 0600), 'delims' (the left and right delimiters, e.g. ['[[', ']]'])
+	// and 'skip' (true to not render the file at all)
+	FrontMatterOptionsKey = "render"
+	// defaultFileMode is the file mode of the outputs if the front matter sets none
+	defaultFileMode os.FileMode = 0644
+)
+
+// WithFrontMatter mutates Renderer configuration by enabling the front matter, an optional YAML block
+// at the beginning of a template, between two FrontMatterDelim lines, which is stripped before rendering,
+// its keys are the file-local defaults of the parameters (the parameters override them),
+// except FrontMatterOptionsKey with the per-file options; it is not enabled by default,
+// because e.g. a multi-document YAML file starts the same way
+func WithFrontMatter() Option {
+	return func(c *extraConfig) {
+		c.frontMatter = true
+	}
+}
+
+// frontMatter is the parsed front matter of a template, see also WithFrontMatter
+type frontMatter struct {
+	defaults   map[string]interface{}
+	output     string
+	mode       os.FileMode // zero if not set
+	leftDelim  string
+	rightDelim string
+	skip       bool
+}
+
+// frontMatterOptions are the options under FrontMatterOptionsKey
+type frontMatterOptions struct {
+	Output string      `json:"output"`
+	Mode   interface{} `json:"mode"`
+	Delims []string    `json:"delims"`
+	Skip   bool        `json:"skip"`
+}
+
+// templateFile is a template with the front matter stripped, see also readTemplate
+type templateFile struct {
+	name       string
+	text       string
+	front      *frontMatter // nil if there is no front matter
+	leftDelim  string       // empty if the delimiters of the renderer are used
+	rightDelim string
+}
+
+// readTemplate reads the template file and parses its front matter if enabled, stdin is used if the path is empty
+func (r *renderer) readTemplate(inputPath string) (templateFile, error) {
+	input, err := files.ReadInput(inputPath)
+	if err != nil {
+		logrus.Debugf("Can't open the template: %v", err)
+		return templateFile{}, err
+	}
+
+	t := templateFile{name: inputPath, text: string(input)}
+	if inputPath == "" {
+		t.name = "stdin"
+	}
+	logrus.Debugf("%s: \n%s", t.name, t.text)
+	if left, right, ok := r.delimsOf(inputPath); ok && inputPath != "" {
+		t.leftDelim, t.rightDelim = left, right
+	}
+	if !r.extra.frontMatter {
+		return t, nil
+	}
+
+	header, body, ok := splitFrontMatter(t.text)
+	if !ok {
+		return t, nil
+	}
+	t.front, err = parseFrontMatter(header)
+	if err != nil {
+		return templateFile{}, errors.Wrapf(err, "can't parse the front matter of '%s'", t.name)
+	}
+	t.text = body
+	if len(t.front.leftDelim) > 0 {
+		t.leftDelim, t.rightDelim = t.front.leftDelim, t.front.rightDelim
+	}
+	return t, nil
+}
+
+// mode returns the file mode of the output set in the front matter, zero if none
+func (t templateFile) mode() os.FileMode {
+	if t.front == nil {
+		return 0
+	}
+	return t.front.mode
+}
+
+// renderTemplate renders the template with the front matter defaults underneath the parameters
+// and with the delimiters of the template, see also WithExtensionDelims
+func (r *renderer) renderTemplate(t templateFile) (string, error) {
+	var configurators []func(*config.Config)
+	if t.front != nil {
+		params, err := parameters.Merge(t.front.defaults, r.Configuration().Parameters)
+		if err != nil {
+			return "", errors.Wrapf(err, "can't merge the front matter of '%s'", t.name)
+		}
+		configurators = append(configurators, WithParameters(params))
+	}
+	if len(t.leftDelim) > 0 {
+		configurators = append(configurators, WithDelim(t.leftDelim, t.rightDelim))
+	}
+	if len(configurators) == 0 {
+		return r.NamedRender(t.name, t.text)
+	}
+	return r.clone(configurators...).NamedRender(t.name, t.text)
+}
+
+// splitFrontMatter splits the text into the front matter and the rest of the text,
+// returns false if the text doesn't start with a front matter
+func splitFrontMatter(text string) (string, string, bool) {
+	first := strings.SplitN(text, "\n", 2)
+	if len(first) < 2 || strings.TrimSuffix(first[0], "\r") != FrontMatterDelim {
+		return "", text, false
+	}
+	rest := first[1]
+	for offset := 0; offset < len(rest); {
+		end := strings.IndexByte(rest[offset:], '\n')
+		line := rest[offset:]
+		if end >= 0 {
+			line = rest[offset : offset+end]
+		}
+		if strings.TrimSuffix(line, "\r") == FrontMatterDelim {
+			if end < 0 {
+				return rest[:offset], "", true
+			}
+			return rest[:offset], rest[offset+end+1:], true
+		}
+		if end < 0 {
+			break
+		}
+		offset += end + 1
+	}
+	return "", text, false
+}
+
+// parseFrontMatter parses the YAML front matter, see also FrontMatterOptionsKey
+func parseFrontMatter(header string) (*frontMatter, error) {
+	var values map[string]interface{}
+	err := yaml.Unmarshal([]byte(header), &values)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	front := &frontMatter{defaults: make(map[string]interface{}, len(values))}
+	for k, v := range values {
+		if k != FrontMatterOptionsKey {
+			front.defaults[k] = v
+		}
+	}
+	if values[FrontMatterOptionsKey] == nil {
+		return front, nil
+	}
+
+	b, err := json.Marshal(values[FrontMatterOptionsKey])
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	decoder := json.NewDecoder(bytes.NewReader(b))
+	decoder.DisallowUnknownFields()
+	var options frontMatterOptions
+	err = decoder.Decode(&options)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid '%s' options", FrontMatterOptionsKey)
+	}
+
+	front.skip = options.Skip
+	if len(options.Output) > 0 {
+		output := path.Clean(options.Output)
+		if path.IsAbs(output) || output == "." || output == ".." || strings.HasPrefix(output, "../") {
+			return nil, errors.Errorf("invalid output path '%s': expected a file path relative to the output directory of the template",
+				options.Output)
+		}
+		front.output = output
+	}
+	front.mode, err = parseFileMode(options.Mode)
+	if err != nil {
+		return nil, err
+	}
+	if options.Delims != nil {
+		if len(options.Delims) != 2 || len(options.Delims[0]) == 0 || len(options.Delims[1]) == 0 {
+			return nil, errors.Errorf("invalid delims '%v': expected the left and the right delimiter", options.Delims)
+		}
+		front.leftDelim, front.rightDelim = options.Delims[0], options.Delims[1]
+	}
+	return front, nil
+}
+
+// parseFileMode parses the file mode, either a number, e.g. 0600 in YAML, or an octal string, e.g. '600'
+func parseFileMode(value interface{}) (os.FileMode, error) {
+	var mode uint64
+	switch v := value.(type) {
+	case nil:
+		return 0, nil
+	case float64:
+		mode = uint64(v)
+		if float64(mode) != v {
+			return 0, errors.Errorf("invalid mode '%v': expected the permission bits, e.g. 0644", value)
+		}
+	case string:
+		var err error
+		mode, err = strconv.ParseUint(v, 8, 32)
+		if err != nil {
+			return 0, errors.Errorf("invalid mode '%s': expected the octal permission bits, e.g. '0644'", v)
+		}
+	default:
+		return 0, errors.Errorf("invalid mode '%v': expected the permission bits, e.g. 0644", value)
+	}
+	if mode == 0 || mode > uint64(os.ModePerm) {
+		return 0, errors.Errorf("invalid mode '%#o': expected the permission bits, e.g. 0644", mode)
+	}
+	return os.FileMode(mode), nil
+}
+
+// writeOutput writes the output file with the file mode, or with the default mode if it is zero,
+// the mode of an existing file is changed as well, stdout is used if the path is empty
+func writeOutput(outputPath string, content []byte, mode os.FileMode) error {
+	perm := mode
+	if perm == 0 {
+		perm = defaultFileMode
+	}
+	err := files.WriteOutput(outputPath, content, perm)
+	if err != nil {
+		logrus.Debugf("Can't save the rendered file: %v", err)
+		return err
+	}
+	if mode == 0 || outputPath == "" {
+		return nil
+	}
+	// the mode of an existing file is not changed by files.WriteOutput
+	return errors.WithStack(os.Chmod(outputPath, mode))
+}
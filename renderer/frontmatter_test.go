@@ -0,0 +1,178 @@
+package renderer
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSplitFrontMatter(t *testing.T) {
+	header, body, ok := splitFrontMatter("---\ntitle: a\n---\nbody\n")
+	assert.True(t, ok)
+	assert.Equal(t, "title: a\n", header)
+	assert.Equal(t, "body\n", body)
+
+	header, body, ok = splitFrontMatter("---\r\ntitle: a\r\n---\r\nbody")
+	assert.True(t, ok)
+	assert.Equal(t, "title: a\r\n", header)
+	assert.Equal(t, "body", body)
+
+	header, body, ok = splitFrontMatter("---\n---")
+	assert.True(t, ok)
+	assert.Equal(t, "", header)
+	assert.Equal(t, "", body)
+
+	_, body, ok = splitFrontMatter("---\nkind: Service\n")
+	assert.False(t, ok, "no closing line")
+	assert.Equal(t, "---\nkind: Service\n", body)
+
+	_, _, ok = splitFrontMatter("body\n---\nmore\n---\n")
+	assert.False(t, ok, "not at the beginning")
+}
+
+func TestRenderer_FileRender_FrontMatter(t *testing.T) {
+	params := map[string]interface{}{"name": "global"}
+
+	Run(t, Test{
+		name: "front matter defaults underneath the parameters",
+		f: func(tt Test) {
+			inputDir, outputDir := tempDirs(t)
+			defer cleanup(inputDir, outputDir)
+			writeTree(t, inputDir, map[string]string{
+				"a.tmpl": "---\nname: local\nport: 8080\nrender:\n  mode: 0600\n---\n{{ .name }}:{{ .port }}",
+			})
+			outputPath := filepath.Join(outputDir, "a")
+
+			err := NewWithOptions([]Option{WithFrontMatter()}, WithParameters(params)).FileRender(filepath.Join(inputDir, "a.tmpl"), outputPath)
+
+			assert.NoError(t, err, tt.name)
+			assert.Equal(t, map[string]string{"a": "global:8080"}, readTree(t, outputDir))
+			info, err := os.Stat(outputPath)
+			require.NoError(t, err)
+			assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+		},
+	})
+
+	Run(t, Test{
+		name: "front matter delimiters",
+		f: func(tt Test) {
+			inputDir, outputDir := tempDirs(t)
+			defer cleanup(inputDir, outputDir)
+			writeTree(t, inputDir, map[string]string{
+				"a.tmpl": "---\nrender:\n  delims: ['[[', ']]']\n---\n[[ .name ]] {{ .Values.x }}",
+			})
+
+			err := NewWithOptions([]Option{WithFrontMatter()}, WithParameters(params)).FileRender(
+				filepath.Join(inputDir, "a.tmpl"), filepath.Join(outputDir, "a"))
+
+			assert.NoError(t, err, tt.name)
+			assert.Equal(t, map[string]string{"a": "global {{ .Values.x }}"}, readTree(t, outputDir))
+		},
+	})
+
+	Run(t, Test{
+		name: "front matter is not stripped unless enabled",
+		f: func(tt Test) {
+			inputDir, outputDir := tempDirs(t)
+			defer cleanup(inputDir, outputDir)
+			writeTree(t, inputDir, map[string]string{"a.yaml": "---\nkind: A\n---\nkind: {{ .name }}\n"})
+
+			err := New(WithParameters(params)).FileRender(filepath.Join(inputDir, "a.yaml"), filepath.Join(outputDir, "a.yaml"))
+
+			assert.NoError(t, err, tt.name)
+			assert.Equal(t, map[string]string{"a.yaml": "---\nkind: A\n---\nkind: global"}, readTree(t, outputDir))
+		},
+	})
+
+	Run(t, Test{
+		name: "front matter skip",
+		f: func(tt Test) {
+			inputDir, outputDir := tempDirs(t)
+			defer cleanup(inputDir, outputDir)
+			writeTree(t, inputDir, map[string]string{"a.tmpl": "---\nrender:\n  skip: true\n---\n{{ .missing }}"})
+
+			err := NewWithOptions([]Option{WithFrontMatter()}, WithParameters(params)).FileRender(
+				filepath.Join(inputDir, "a.tmpl"), filepath.Join(outputDir, "a"))
+
+			assert.NoError(t, err, tt.name)
+			assert.Empty(t, readTree(t, outputDir))
+		},
+	})
+
+	Run(t, Test{
+		name: "invalid front matter options",
+		f: func(tt Test) {
+			inputDir, outputDir := tempDirs(t)
+			defer cleanup(inputDir, outputDir)
+			writeTree(t, inputDir, map[string]string{
+				"unknown.tmpl": "---\nrender:\n  unknown: true\n---\n",
+				"mode.tmpl":    "---\nrender:\n  mode: 644\n---\n",
+				"delims.tmpl":  "---\nrender:\n  delims: ['[[']\n---\n",
+				"output.tmpl":  "---\nrender:\n  output: ../a\n---\n",
+			})
+			r := NewWithOptions([]Option{WithFrontMatter()})
+
+			for name, message := range map[string]string{
+				"unknown.tmpl": "invalid 'render' options",
+				"mode.tmpl":    "invalid mode '01204'",
+				"delims.tmpl":  "invalid delims '[[[]'",
+				"output.tmpl":  "invalid output path '../a'",
+			} {
+				err := r.FileRender(filepath.Join(inputDir, name), filepath.Join(outputDir, name))
+				if assert.Error(t, err, name) {
+					assert.Contains(t, err.Error(), "can't parse the front matter", name)
+					assert.Contains(t, err.Error(), message, name)
+				}
+			}
+		},
+	})
+}
+
+func TestRenderer_DirRender_FrontMatter(t *testing.T) {
+	Run(t, Test{
+		name: "front matter options in the directory mode",
+		f: func(tt Test) {
+			inputDir, outputDir := tempDirs(t)
+			defer cleanup(inputDir, outputDir)
+			writeTree(t, inputDir, map[string]string{
+				"a.yaml.tmpl":       "---\nname: a\n---\nname: {{ .name }}\n",
+				"b/b.yaml.tmpl":     "---\nrender:\n  output: renamed/c.yaml\n  mode: '0640'\n---\nname: {{ .name }}\n",
+				"skipped.yaml.tmpl": "---\nrender:\n  skip: true\n---\n{{ .missing }}",
+				"copied.yaml":       "---\nrender:\n  skip: true\n---\n",
+			})
+
+			err := NewWithOptions([]Option{WithFrontMatter()}, WithParameters(map[string]interface{}{"name": "global"})).DirRender(inputDir, outputDir)
+
+			assert.NoError(t, err, tt.name)
+			assert.Equal(t, map[string]string{
+				"a.yaml":           "name: global",
+				"b/renamed/c.yaml": "name: global",
+				"copied.yaml":      "---\nrender:\n  skip: true\n---\n",
+			}, readTree(t, outputDir))
+			info, err := os.Stat(filepath.Join(outputDir, "b", "renamed", "c.yaml"))
+			require.NoError(t, err)
+			assert.Equal(t, os.FileMode(0640), info.Mode().Perm())
+		},
+	})
+	Run(t, Test{
+		name: "front matter templates are read once in the directory mode",
+		f: func(tt Test) {
+			inputDir, outputDir := tempDirs(t)
+			defer cleanup(inputDir, outputDir)
+			writeTree(t, inputDir, map[string]string{"a.yaml.tmpl": "---\nname: a\n---\nname: {{ .name }}\n"})
+			r := NewWithOptions([]Option{WithFrontMatter()}).(*renderer)
+
+			tasks, err := r.dirTasks(inputDir, outputDir, nil)
+			require.NoError(t, err, tt.name)
+			require.Len(t, tasks, 1)
+			writeTree(t, inputDir, map[string]string{"a.yaml.tmpl": "changed"})
+			result := <-r.renderTasks(tasks)[0]
+
+			assert.NoError(t, result.err, tt.name)
+			assert.Equal(t, "name: a", result.content)
+		},
+	})
+}
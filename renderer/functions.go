@@ -97,6 +97,9 @@ func (r *renderer) ReadFile(file string) (string, error) {
 	if err != nil {
 		return "", err
 	}
+	if r.extra.onReadFile != nil {
+		r.extra.onReadFile(absPath)
+	}
 	bs, err := ioutil.ReadFile(absPath)
 	if err != nil {
 		return "", err
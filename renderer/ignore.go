@@ -0,0 +1,147 @@
+package renderer
+
+import (
+	"bufio"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// IgnoreFileName is the name of the files with gitignore-style patterns of paths
+// to be skipped in the directory mode, the files can be placed on any level of the input directory tree
+const IgnoreFileName = ".renderignore"
+
+// ignoreRule is a single gitignore-style pattern
+type ignoreRule struct {
+	base     string // slash separated directory the rule is relative to, empty for the input directory
+	segments []string
+	negate   bool
+	dirOnly  bool
+	anchored bool
+}
+
+// ignoreRules is an ordered list of ignore rules, the last matching rule wins
+type ignoreRules []ignoreRule
+
+// parseIgnoreRule parses a single gitignore-style pattern line, returns false for blank lines and comments
+func parseIgnoreRule(base, line string) (ignoreRule, bool) {
+	line = strings.TrimRight(line, " \t\r")
+	if len(line) == 0 || strings.HasPrefix(line, "#") {
+		return ignoreRule{}, false
+	}
+
+	rule := ignoreRule{base: base}
+	if strings.HasPrefix(line, "!") {
+		rule.negate = true
+		line = line[1:]
+	} else if strings.HasPrefix(line, `\!`) || strings.HasPrefix(line, `\#`) {
+		line = line[1:]
+	}
+	if strings.HasSuffix(line, "/") {
+		rule.dirOnly = true
+		line = strings.TrimRight(line, "/")
+	}
+	if strings.Contains(line, "/") {
+		rule.anchored = true
+		line = strings.TrimLeft(line, "/")
+	}
+	if len(line) == 0 {
+		return ignoreRule{}, false
+	}
+	rule.segments = strings.Split(line, "/")
+	return rule, true
+}
+
+// withPatterns returns new rules extended with the given patterns relative to the given base
+func (rules ignoreRules) withPatterns(base string, patterns []string) ignoreRules {
+	extended := append(ignoreRules{}, rules...)
+	for _, pattern := range patterns {
+		if rule, ok := parseIgnoreRule(base, pattern); ok {
+			extended = append(extended, rule)
+		}
+	}
+	return extended
+}
+
+// withFile returns new rules extended with the patterns from the ignore file in the given directory,
+// if there is no ignore file the rules are returned unchanged
+func (rules ignoreRules) withFile(inputDir, rel string) (ignoreRules, error) {
+	ignorePath := filepath.Join(inputDir, filepath.FromSlash(rel), IgnoreFileName)
+	f, err := os.Open(ignorePath)
+	if os.IsNotExist(err) {
+		return rules, nil
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "can't open the ignore file: '%s'", ignorePath)
+	}
+	defer func() { _ = f.Close() }()
+
+	logrus.Debugf("Reading ignore file: '%s'", ignorePath)
+	var patterns []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		patterns = append(patterns, scanner.Text())
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, errors.Wrapf(err, "can't read the ignore file: '%s'", ignorePath)
+	}
+	return rules.withPatterns(rel, patterns), nil
+}
+
+// ignored checks if the given slash separated path, relative to the input directory, should be skipped
+func (rules ignoreRules) ignored(rel string, isDir bool) bool {
+	ignored := false
+	for _, rule := range rules {
+		if rule.matches(rel, isDir) {
+			ignored = !rule.negate
+		}
+	}
+	return ignored
+}
+
+func (rule ignoreRule) matches(rel string, isDir bool) bool {
+	if rule.dirOnly && !isDir {
+		return false
+	}
+	if len(rule.base) > 0 {
+		if !strings.HasPrefix(rel, rule.base+"/") {
+			return false
+		}
+		rel = strings.TrimPrefix(rel, rule.base+"/")
+	}
+	if !rule.anchored {
+		return matchSegments(rule.segments, []string{path.Base(rel)})
+	}
+	return matchSegments(rule.segments, strings.Split(rel, "/"))
+}
+
+// matchSegments matches path segments against pattern segments,
+// the '**' pattern segment matches zero or more path segments, the trailing one matches one or more
+func matchSegments(pattern, segments []string) bool {
+	if len(pattern) == 0 {
+		return len(segments) == 0
+	}
+	if pattern[0] == "**" {
+		if len(pattern) == 1 {
+			return len(segments) > 0
+		}
+		for i := 0; i <= len(segments); i++ {
+			if matchSegments(pattern[1:], segments[i:]) {
+				return true
+			}
+		}
+		return false
+	}
+	if len(segments) == 0 {
+		return false
+	}
+	matched, err := path.Match(pattern[0], segments[0])
+	if err != nil || !matched {
+		return false
+	}
+	return matchSegments(pattern[1:], segments[1:])
+}
@@ -0,0 +1,81 @@
+package renderer
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIgnoreRules_Ignored(t *testing.T) {
+	type test struct {
+		name     string
+		base     string
+		patterns []string
+		path     string
+		isDir    bool
+		want     bool
+	}
+
+	tests := []test{
+		{name: "no patterns", path: "a.tmpl", want: false},
+		{name: "comment", patterns: []string{"# a.tmpl"}, path: "a.tmpl", want: false},
+		{name: "simple", patterns: []string{"a.tmpl"}, path: "a.tmpl", want: true},
+		{name: "wildcard", patterns: []string{"*.swp"}, path: "a.swp", want: true},
+		{name: "wildcard nested", patterns: []string{"*.swp"}, path: "sub/dir/a.swp", want: true},
+		{name: "wildcard no match", patterns: []string{"*.swp"}, path: "a.tmpl", want: false},
+		{name: "negation", patterns: []string{"*.yaml", "!keep.yaml"}, path: "keep.yaml", want: false},
+		{name: "negation last wins", patterns: []string{"!keep.yaml", "*.yaml"}, path: "keep.yaml", want: true},
+		{name: "dir only on dir", patterns: []string{".git/"}, path: ".git", isDir: true, want: true},
+		{name: "dir only on file", patterns: []string{".git/"}, path: ".git", want: false},
+		{name: "anchored", patterns: []string{"/a.tmpl"}, path: "a.tmpl", want: true},
+		{name: "anchored nested", patterns: []string{"/a.tmpl"}, path: "sub/a.tmpl", want: false},
+		{name: "anchored path", patterns: []string{"sub/a.tmpl"}, path: "sub/a.tmpl", want: true},
+		{name: "double star prefix", patterns: []string{"**/a.tmpl"}, path: "x/y/a.tmpl", want: true},
+		{name: "double star middle", patterns: []string{"x/**/a.tmpl"}, path: "x/a.tmpl", want: true},
+		{name: "double star suffix", patterns: []string{"x/**"}, path: "x/y/a.tmpl", want: true},
+		{name: "double star suffix dir itself", patterns: []string{"x/**"}, path: "x", isDir: true, want: false},
+		{name: "base", base: "sub", patterns: []string{"a.tmpl"}, path: "sub/a.tmpl", want: true},
+		{name: "base outside", base: "sub", patterns: []string{"a.tmpl"}, path: "a.tmpl", want: false},
+		{name: "base anchored", base: "sub", patterns: []string{"/a.tmpl"}, path: "sub/a.tmpl", want: true},
+	}
+
+	for i, tt := range tests {
+		t.Run(fmt.Sprintf("[%d] %s", i, tt.name), func(t *testing.T) {
+			rules := ignoreRules{}.withPatterns(tt.base, tt.patterns)
+			assert.Equal(t, tt.want, rules.ignored(tt.path, tt.isDir))
+		})
+	}
+}
+
+func TestRenderer_DirRender_Ignore(t *testing.T) {
+	Run(t, Test{
+		name: "dir render with ignore",
+		f: func(tt Test) {
+			inputDir, outputDir := tempDirs(t)
+			defer cleanup(inputDir, outputDir)
+			writeTree(t, inputDir, map[string]string{
+				".renderignore":       "*.bak\nskipped/\n",
+				"a.yaml.tmpl":         "{{ .value }}",
+				"a.yaml.bak":          "{{ broken",
+				"skipped/b.yaml.tmpl": "{{ broken",
+				"sub/.renderignore":   "!keep.bak\nc.yaml.tmpl\n",
+				"sub/c.yaml.tmpl":     "{{ broken",
+				"sub/keep.bak":        "{{ .value }}",
+				"sub/d.txt":           "{{ broken",
+			})
+
+			err := NewWithOptions(
+				[]Option{WithDirMode(RenderAllMode), WithIgnorePatterns("d.txt")},
+				WithParameters(map[string]interface{}{"value": "some"}),
+			).DirRender(inputDir, outputDir)
+
+			assert.NoError(t, err, tt.name)
+			assert.Equal(t, map[string]string{
+				"a.yaml":       "some",
+				"sub/keep.bak": "some",
+			}, readTree(t, outputDir))
+			assert.Equal(t, 0, CountProblems(tt.logHook))
+		},
+	})
+}
@@ -1,6 +1,6 @@
 /*
 Package parameters defines data structure for the data-driven renderer
 
-Parameters is a tree structure and can be created from a YAML files or 'key=value' pairs.
+Parameters is a tree structure and can be created from YAML, JSON, TOML or dotenv files or 'key=value' pairs.
 */
 package parameters
@@ -0,0 +1,197 @@
+package parameters
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"github.com/ghodss/yaml"
+	"github.com/pkg/errors"
+	yamlv3 "gopkg.in/yaml.v3"
+)
+
+// Format is a configuration file format, see also FormatOf
+type Format string
+
+const (
+	// YAMLFormat is the default configuration file format
+	YAMLFormat Format = "yaml"
+	// JSONFormat is the JSON configuration file format, the errors are reported with a line and a column
+	JSONFormat Format = "json"
+	// TOMLFormat is the TOML configuration file format
+	TOMLFormat Format = "toml"
+	// EnvFormat is the dotenv 'KEY=value' configuration file format, the keys are not nested
+	EnvFormat Format = "env"
+)
+
+// Formats returns all the supported configuration file formats
+func Formats() []Format {
+	return []Format{YAMLFormat, JSONFormat, TOMLFormat, EnvFormat}
+}
+
+// FormatOf returns the format and the path of the configuration file, the format is either
+// set explicitly with a 'format:path' prefix, e.g. 'toml:config.txt', or detected from the file extension,
+// YAMLFormat is used for the unknown extensions
+func FormatOf(configPath string) (Format, string) {
+	if i := strings.Index(configPath, ":"); i > 0 {
+		format := Format(strings.ToLower(configPath[:i]))
+		for _, f := range Formats() {
+			if format == f {
+				return format, configPath[i+1:]
+			}
+		}
+	}
+
+	name := strings.ToLower(filepath.Base(configPath))
+	switch {
+	case strings.HasSuffix(name, ".json"):
+		return JSONFormat, configPath
+	case strings.HasSuffix(name, ".toml"):
+		return TOMLFormat, configPath
+	case strings.HasSuffix(name, ".env") || strings.HasPrefix(name, ".env."):
+		return EnvFormat, configPath
+	default:
+		return YAMLFormat, configPath
+	}
+}
+
+// unmarshal parses the configuration file content in the given format
+func unmarshal(format Format, b []byte) (map[string]interface{}, error) {
+	config := make(map[string]interface{})
+	switch format {
+	case YAMLFormat:
+		err := yaml.Unmarshal(b, &config)
+		return config, errors.WithStack(err)
+	case JSONFormat:
+		err := unmarshalJSON(b, &config)
+		return config, err
+	case TOMLFormat:
+		_, err := toml.Decode(string(b), &config)
+		return config, errors.WithStack(err)
+	case EnvFormat:
+		config, _, err := unmarshalEnv(b)
+		return config, err
+	default:
+		return nil, errors.Errorf("unexpected configuration format: '%s', format must be in: '%s'", format, Formats())
+	}
+}
+
+// unmarshalJSON parses JSON and reports the position of the syntax and type errors
+func unmarshalJSON(b []byte, config *map[string]interface{}) error {
+	err := json.Unmarshal(b, config)
+	var offset int64
+	switch e := err.(type) {
+	case nil:
+		return nil
+	case *json.SyntaxError:
+		offset = e.Offset
+	case *json.UnmarshalTypeError:
+		offset = e.Offset
+	default:
+		return errors.WithStack(err)
+	}
+	line, column := position(b, offset)
+	return errors.Errorf("line %d, column %d: %v", line, column, err)
+}
+
+// position returns the 1-based line and column of the byte offset
+func position(b []byte, offset int64) (line, column int) {
+	if offset > int64(len(b)) {
+		offset = int64(len(b))
+	}
+	before := b[:offset]
+	line = bytes.Count(before, []byte("\n")) + 1
+	column = len(before) - bytes.LastIndexByte(before, '\n')
+	return line, column
+}
+
+// unmarshalEnv parses the 'KEY=value' lines, with the optional 'export' prefix, '#' comments and quoted values,
+// the line numbers of the keys are also returned
+func unmarshalEnv(b []byte) (map[string]interface{}, map[string]int, error) {
+	config := make(map[string]interface{})
+	lines := make(map[string]int)
+	scanner := bufio.NewScanner(bytes.NewReader(b))
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := strings.TrimSpace(scanner.Text())
+		if len(line) == 0 || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimSpace(strings.TrimPrefix(line, "export "))
+
+		i := strings.Index(line, "=")
+		if i <= 0 {
+			return nil, nil, errors.Errorf("line %d: expected 'KEY=value', got: '%s'", lineNumber, line)
+		}
+		key := strings.TrimSpace(line[:i])
+		value := strings.TrimSpace(line[i+1:])
+
+		switch {
+		case strings.HasPrefix(value, `"`):
+			unquoted, err := strconv.Unquote(value)
+			if err != nil {
+				return nil, nil, errors.Errorf("line %d: invalid double quoted value: '%s'", lineNumber, value)
+			}
+			value = unquoted
+		case strings.HasPrefix(value, `'`):
+			if len(value) < 2 || !strings.HasSuffix(value, `'`) {
+				return nil, nil, errors.Errorf("line %d: invalid single quoted value: '%s'", lineNumber, value)
+			}
+			value = value[1 : len(value)-1]
+		default:
+			if j := strings.Index(value, " #"); j >= 0 {
+				value = strings.TrimSpace(value[:j])
+			}
+		}
+		config[key] = value
+		lines["/"+escapePointer(key)] = lineNumber
+	}
+	return config, lines, errors.WithStack(scanner.Err())
+}
+
+// lineNumbers returns the line numbers of the values in the configuration file content, keyed by the JSON pointers,
+// the lines are not known for the TOML format
+func lineNumbers(format Format, b []byte) map[string]int {
+	lines := make(map[string]int)
+	switch format {
+	case YAMLFormat, JSONFormat:
+		var document yamlv3.Node
+		if yamlv3.Unmarshal(b, &document) == nil {
+			nodeLines(lines, "", &document)
+		}
+	case EnvFormat:
+		_, envLines, err := unmarshalEnv(b)
+		if err == nil {
+			lines = envLines
+		}
+	}
+	return lines
+}
+
+// nodeLines records the line numbers of the YAML node and its children, JSON is parsed as YAML
+func nodeLines(lines map[string]int, pointer string, node *yamlv3.Node) {
+	switch node.Kind {
+	case yamlv3.DocumentNode:
+		for _, child := range node.Content {
+			nodeLines(lines, pointer, child)
+		}
+	case yamlv3.AliasNode:
+		nodeLines(lines, pointer, node.Alias)
+	case yamlv3.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, value := node.Content[i], node.Content[i+1]
+			childPointer := pointer + "/" + escapePointer(key.Value)
+			lines[childPointer] = key.Line
+			nodeLines(lines, childPointer, value)
+		}
+	case yamlv3.SequenceNode:
+		for i, child := range node.Content {
+			childPointer := pointer + "/" + strconv.Itoa(i)
+			lines[childPointer] = child.Line
+			nodeLines(lines, childPointer, child)
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package parameters
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFormatOf(t *testing.T) {
+	type test struct {
+		path       string
+		wantFormat Format
+		wantPath   string
+	}
+
+	tests := []test{
+		{path: "config.yaml", wantFormat: YAMLFormat, wantPath: "config.yaml"},
+		{path: "config.yml", wantFormat: YAMLFormat, wantPath: "config.yml"},
+		{path: "config.JSON", wantFormat: JSONFormat, wantPath: "config.JSON"},
+		{path: "dir/config.toml", wantFormat: TOMLFormat, wantPath: "dir/config.toml"},
+		{path: ".env", wantFormat: EnvFormat, wantPath: ".env"},
+		{path: "prod.env", wantFormat: EnvFormat, wantPath: "prod.env"},
+		{path: ".env.local", wantFormat: EnvFormat, wantPath: ".env.local"},
+		{path: "config.txt", wantFormat: YAMLFormat, wantPath: "config.txt"},
+		{path: "toml:config.txt", wantFormat: TOMLFormat, wantPath: "config.txt"},
+		{path: "JSON:config.yaml", wantFormat: JSONFormat, wantPath: "config.yaml"},
+		{path: "unknown:config.json", wantFormat: JSONFormat, wantPath: "unknown:config.json"},
+	}
+
+	for i, tt := range tests {
+		t.Run(fmt.Sprintf("[%d] %s", i, tt.path), func(t *testing.T) {
+			format, path := FormatOf(tt.path)
+			assert.Equal(t, tt.wantFormat, format)
+			assert.Equal(t, tt.wantPath, path)
+		})
+	}
+}
+
+func TestFromFiles_Formats(t *testing.T) {
+	dir := tempDir(t)
+
+	yamlPath := writeFile(t, dir, "a.yaml", "db:\n  host: yaml\n  port: 1\nname: yaml\n")
+	jsonPath := writeFile(t, dir, "b.json", `{"db": {"host": "json"}, "list": [1, 2]}`)
+	tomlPath := writeFile(t, dir, "c.toml", "title = \"toml\"\n[db]\nuser = \"admin\"\n")
+	envPath := writeFile(t, dir, ".env", "# comment\nexport NAME=env\nQUOTED=\"a b\\n\"\nSINGLE='c d'\nPLAIN=e # comment\n")
+	txtPath := writeFile(t, dir, "d.txt", "[server]\nport = 8080\n")
+
+	got, err := FromFiles([]string{yamlPath, jsonPath, tomlPath, envPath, "toml:" + txtPath})
+	assert.NoError(t, err)
+	assert.Equal(t, Parameters{
+		"db": map[string]interface{}{
+			"host": "json",
+			"port": float64(1),
+			"user": "admin",
+		},
+		"name":   "yaml",
+		"list":   []interface{}{float64(1), float64(2)},
+		"title":  "toml",
+		"NAME":   "env",
+		"QUOTED": "a b\n",
+		"SINGLE": "c d",
+		"PLAIN":  "e",
+		"server": map[string]interface{}{"port": int64(8080)},
+	}, got)
+
+	t.Run("json error position", func(t *testing.T) {
+		brokenPath := writeFile(t, dir, "broken.json", "{\n  \"a\": 1,\n  \"b\": ]\n}")
+		_, err := FromFiles([]string{brokenPath})
+		assert.EqualError(t, err, "can't parse the json configuration file '"+brokenPath+
+			"': line 3, column 9: invalid character ']' looking for beginning of value")
+	})
+
+	t.Run("env error position", func(t *testing.T) {
+		brokenPath := writeFile(t, dir, "broken.env", "A=1\nB\n")
+		_, err := FromFiles([]string{brokenPath})
+		assert.EqualError(t, err, "can't parse the env configuration file '"+brokenPath+
+			"': line 2: expected 'KEY=value', got: 'B'")
+	})
+}
@@ -0,0 +1,122 @@
+package parameters
+
+import (
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// ImportsKey is a reserved configuration file key with the path or the list of paths of the imported
+// configuration files, relative to the importing file, the imported files are merged (in order) before
+// the importing file, recursively, e.g. 'imports: [common.yaml, ../shared/net.yaml]'
+const ImportsKey = "imports"
+
+// importsOf removes the imports from the configuration file content and returns the imported paths,
+// the relative paths are resolved against the directory of the importing file, the format prefixes are kept,
+// any other value than a path or a list of paths is an error, so the reserved key is never consumed silently
+func importsOf(configPath string, config map[string]interface{}) ([]string, error) {
+	value, ok := config[ImportsKey]
+	if !ok {
+		return nil, nil
+	}
+	delete(config, ImportsKey)
+
+	var paths []string
+	switch v := value.(type) {
+	case string:
+		paths = []string{v}
+	case []interface{}:
+		for _, e := range v {
+			path, ok := e.(string)
+			if !ok {
+				return nil, errors.Errorf("invalid '%s' in the configuration file '%s': expected a path, got: '%v'",
+					ImportsKey, configPath, e)
+			}
+			paths = append(paths, path)
+		}
+	default:
+		return nil, errors.Errorf("invalid '%s' in the configuration file '%s': expected a path or a list of paths, got: '%T'",
+			ImportsKey, configPath, value)
+	}
+
+	imports := make([]string, len(paths))
+	for i, path := range paths {
+		format, p := FormatOf(path)
+		prefixed := p != path
+		if !filepath.IsAbs(p) {
+			p = filepath.Join(filepath.Dir(configPath), p)
+		}
+		if prefixed {
+			p = string(format) + ":" + p
+		}
+		imports[i] = p
+	}
+	return imports, nil
+}
+
+// pushImport returns the stack of the importing files with the configuration file pushed,
+// or an error if the file is already on the stack
+func pushImport(stack []string, configPath string) ([]string, error) {
+	absPath, err := filepath.Abs(configPath)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	for i, importing := range stack {
+		absImporting, err := filepath.Abs(importing)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		if absImporting == absPath {
+			return nil, errors.Errorf("cycle in the configuration imports: %s",
+				strings.Join(append(append([]string{}, stack[i:]...), configPath), " -> "))
+		}
+	}
+	return append(append([]string{}, stack...), configPath), nil
+}
+
+// ConfigFiles returns the paths of the configuration files and the files they import, recursively,
+// without the format prefixes, each path is listed once, see also ImportsKey
+func ConfigFiles(configPaths []string) ([]string, error) {
+	var result []string
+	seen := make(map[string]bool)
+	var visit func(configPath string, stack []string) error
+	visit = func(configPath string, stack []string) error {
+		_, configPath, _, config, err := readConfig(configPath)
+		if err != nil {
+			return err
+		}
+		absPath, err := filepath.Abs(configPath)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		if !seen[absPath] {
+			seen[absPath] = true
+			result = append(result, configPath)
+		}
+		imports, err := importsOf(configPath, config)
+		if err != nil {
+			return err
+		}
+		if len(imports) > 0 {
+			stack, err = pushImport(stack, configPath)
+			if err != nil {
+				return err
+			}
+		}
+		for _, imported := range imports {
+			err := visit(imported, stack)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	for _, configPath := range configPaths {
+		err := visit(configPath, nil)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
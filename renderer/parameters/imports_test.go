@@ -0,0 +1,67 @@
+package parameters
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFromFiles_Imports(t *testing.T) {
+	dir := tempDir(t)
+
+	writeFile(t, dir, "shared/net.yaml", "imports: common.yaml\nnet:\n  cidr: 10.0.0.0/8\n  name: net\n")
+	writeFile(t, dir, "shared/common.yaml", "name: common\nnet:\n  name: common\n  mtu: 1500\n")
+	writeFile(t, dir, "app/settings.txt", "[db]\nhost = \"localhost\"\n")
+	appPath := writeFile(t, dir, "app/app.yaml", "imports: [../shared/net.yaml, 'toml:settings.txt']\nname: app\n")
+
+	got, err := FromFiles([]string{appPath})
+	assert.NoError(t, err)
+	assert.Equal(t, Parameters{
+		"name": "app",
+		"net": map[string]interface{}{
+			"name": "net",
+			"cidr": "10.0.0.0/8",
+			"mtu":  float64(1500),
+		},
+		"db": map[string]interface{}{"host": "localhost"},
+	}, got)
+
+	t.Run("config files", func(t *testing.T) {
+		configFiles, err := ConfigFiles([]string{"yaml:" + appPath})
+		assert.NoError(t, err)
+		assert.Equal(t, []string{
+			appPath,
+			filepath.Join(dir, "shared/net.yaml"),
+			filepath.Join(dir, "shared/common.yaml"),
+			filepath.Join(dir, "app/settings.txt"),
+		}, configFiles)
+	})
+
+	t.Run("cycle", func(t *testing.T) {
+		aPath := writeFile(t, dir, "cycle/a.yaml", "imports: [b.yaml]\n")
+		bPath := writeFile(t, dir, "cycle/b.yaml", "imports: [a.yaml]\n")
+		_, err := FromFiles([]string{aPath})
+		assert.EqualError(t, err, "cycle in the configuration imports: "+aPath+" -> "+bPath+" -> "+aPath)
+	})
+
+	t.Run("invalid imports", func(t *testing.T) {
+		invalidPath := writeFile(t, dir, "invalid.yaml", "imports: {a: b}\n")
+		_, err := FromFiles([]string{invalidPath})
+		assert.EqualError(t, err, "invalid 'imports' in the configuration file '"+invalidPath+
+			"': expected a path or a list of paths, got: 'map[string]interface {}'")
+	})
+
+	t.Run("empty imports", func(t *testing.T) {
+		emptyPath := writeFile(t, dir, "empty.yaml", "imports:\nname: render\n")
+		_, err := FromFiles([]string{emptyPath})
+		assert.EqualError(t, err, "invalid 'imports' in the configuration file '"+emptyPath+
+			"': expected a path or a list of paths, got: '<nil>'")
+	})
+
+	t.Run("missing import", func(t *testing.T) {
+		missingPath := writeFile(t, dir, "missing.yaml", "imports: [nope.yaml]\n")
+		_, err := FromFiles([]string{missingPath})
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,358 @@
+package parameters
+
+import (
+	"bytes"
+	"encoding/json"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+	"text/template/parse"
+
+	"github.com/pkg/errors"
+)
+
+// RenderFunc renders the template text with the data, see also Interpolate
+type RenderFunc func(data map[string]interface{}, text string) (string, error)
+
+// referenceRegexp matches the '${key}' references and the '$${' escapes
+var referenceRegexp = regexp.MustCompile(`\$?\$\{([^}]*)\}`)
+
+// Interpolate resolves in place the references to the other parameters in the string values,
+// either '${key}' (see also SetVars for the key syntax, '$${' escapes it) or a template, e.g. 'https://{{ .domain }}/api',
+// a value which is a single '${key}' reference gets the referenced value as is, e.g. a number or a map;
+// the referenced values are resolved first, a cycle of references is an error,
+// the templates are rendered with the render function, or with text/template if it is nil,
+// the environment variables under EnvKey are never interpolated, see also InterpolateWithDelims
+func (parameters Parameters) Interpolate(render RenderFunc) error {
+	return parameters.InterpolateWithDelims("{{", "}}", render)
+}
+
+// InterpolateWithDelims resolves the references the same way as Interpolate,
+// with the templates delimited with the left and right delimiters, e.g. '[[' and ']]'
+func (parameters Parameters) InterpolateWithDelims(leftDelim, rightDelim string, render RenderFunc) error {
+	if render == nil {
+		render = func(data map[string]interface{}, text string) (string, error) {
+			return renderTemplate(data, text, leftDelim, rightDelim)
+		}
+	}
+	i := &interpolation{
+		parameters: parameters,
+		render:     render,
+		leftDelim:  leftDelim,
+		rightDelim: rightDelim,
+		resolved:   make(map[string]bool),
+	}
+	keys := make([]string, 0, len(parameters))
+	for key := range parameters {
+		if key != EnvKey {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		err := i.resolveValue([]keySegment{{key: key}}, parameters[key])
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// interpolation holds the state of Parameters.Interpolate
+type interpolation struct {
+	parameters Parameters
+	render     RenderFunc
+	leftDelim  string
+	rightDelim string
+	resolved   map[string]bool // keyed by the JSON pointers
+	stack      [][]keySegment  // the values being resolved, to detect the cycles
+}
+
+// resolvePath resolves the value under the key segments, if there is any
+func (i *interpolation) resolvePath(segments []keySegment) error {
+	if len(segments) == 0 || segments[0].isIndex || segments[0].key == EnvKey {
+		return nil
+	}
+	value, ok := lookup(i.parameters, segments)
+	if !ok {
+		return nil
+	}
+	return i.resolveValue(segments, value)
+}
+
+// resolveValue resolves the string value or all the strings values in the map or list
+func (i *interpolation) resolveValue(segments []keySegment, value interface{}) error {
+	if m, ok := asMap(value); ok {
+		keys := make([]string, 0, len(m))
+		for key := range m {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			err := i.resolveValue(appendSegment(segments, keySegment{key: key}), m[key])
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if list, ok := value.([]interface{}); ok {
+		for index, e := range list {
+			err := i.resolveValue(appendSegment(segments, keySegment{index: index, isIndex: true}), e)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if text, ok := value.(string); ok {
+		return i.resolveString(segments, text)
+	}
+	return nil
+}
+
+// resolveString resolves the references of the string value, the referenced values are resolved first
+func (i *interpolation) resolveString(segments []keySegment, text string) error {
+	pointer := pointerOf(segments)
+	if i.resolved[pointer] {
+		return nil
+	}
+	for j, s := range i.stack {
+		if pointerOf(s) == pointer {
+			cycle := make([]string, 0, len(i.stack)-j+1)
+			for _, s := range append(i.stack[j:], segments) {
+				cycle = append(cycle, keyOf(s))
+			}
+			return errors.Errorf("cycle in the parameter references: %s", strings.Join(cycle, " -> "))
+		}
+	}
+	if !strings.Contains(text, "${") && !strings.Contains(text, i.leftDelim) {
+		i.resolved[pointer] = true
+		return nil
+	}
+
+	i.stack = append(i.stack, segments)
+	references, err := referencesOf(text, i.leftDelim, i.rightDelim)
+	if err != nil {
+		return errors.Wrapf(err, "can't interpolate '%s'", keyOf(segments))
+	}
+	for _, reference := range references {
+		err := i.resolvePath(reference)
+		if err != nil {
+			return err
+		}
+	}
+	value, err := i.evaluate(text)
+	if err != nil {
+		return errors.Wrapf(err, "can't interpolate '%s'", keyOf(segments))
+	}
+	_, err = setNested(i.parameters, segments, value, "")
+	if err != nil {
+		return err
+	}
+	i.stack = i.stack[:len(i.stack)-1]
+	i.resolved[pointer] = true
+	return nil
+}
+
+// evaluate replaces the '${key}' references with the referenced values and renders the template
+func (i *interpolation) evaluate(text string) (interface{}, error) {
+	if match := referenceRegexp.FindStringSubmatch(text); match != nil && match[0] == text && !strings.HasPrefix(text, "$$") {
+		value, err := i.reference(match[1])
+		return deepCopy(value), err
+	}
+
+	var referenceErr error
+	replaced := referenceRegexp.ReplaceAllStringFunc(text, func(match string) string {
+		if strings.HasPrefix(match, "$$") {
+			return match[1:]
+		}
+		value, err := i.reference(match[2 : len(match)-1])
+		if err != nil && referenceErr == nil {
+			referenceErr = err
+		}
+		return stringOf(value)
+	})
+	if referenceErr != nil {
+		return nil, referenceErr
+	}
+	if !strings.Contains(replaced, i.leftDelim) {
+		return replaced, nil
+	}
+	return i.render(map[string]interface{}(i.parameters), replaced)
+}
+
+// reference returns the value under the referenced key
+func (i *interpolation) reference(key string) (interface{}, error) {
+	segments, err := parseKey(strings.TrimSpace(key))
+	if err != nil {
+		return nil, err
+	}
+	value, ok := lookup(i.parameters, segments)
+	if !ok {
+		return nil, errors.Errorf("unknown parameter '%s'", key)
+	}
+	return value, nil
+}
+
+// referencesOf returns the keys referenced by the text, with '${key}' or in the template, e.g. '.domain'
+func referencesOf(text, leftDelim, rightDelim string) ([][]keySegment, error) {
+	var references [][]keySegment
+	for _, match := range referenceRegexp.FindAllStringSubmatch(text, -1) {
+		if strings.HasPrefix(match[0], "$$") {
+			continue
+		}
+		segments, err := parseKey(strings.TrimSpace(match[1]))
+		if err != nil {
+			return nil, err
+		}
+		references = append(references, segments)
+	}
+
+	if !strings.Contains(text, leftDelim) {
+		return references, nil
+	}
+	tree := parse.New("parameter")
+	tree.Mode = parse.SkipFuncCheck
+	_, err := tree.Parse(referenceRegexp.ReplaceAllString(text, ""), leftDelim, rightDelim, make(map[string]*parse.Tree))
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	var walk func(node parse.Node)
+	walk = func(node parse.Node) {
+		switch n := node.(type) {
+		case *parse.ListNode:
+			if n != nil {
+				for _, child := range n.Nodes {
+					walk(child)
+				}
+			}
+		case *parse.ActionNode:
+			walk(n.Pipe)
+		case *parse.PipeNode:
+			if n != nil {
+				for _, command := range n.Cmds {
+					walk(command)
+				}
+			}
+		case *parse.CommandNode:
+			for _, arg := range n.Args {
+				walk(arg)
+			}
+		case *parse.IfNode:
+			walk(n.Pipe)
+			walk(n.List)
+			walk(n.ElseList)
+		case *parse.RangeNode:
+			walk(n.Pipe)
+			walk(n.List)
+			walk(n.ElseList)
+		case *parse.WithNode:
+			walk(n.Pipe)
+			walk(n.List)
+			walk(n.ElseList)
+		case *parse.TemplateNode:
+			walk(n.Pipe)
+		case *parse.FieldNode:
+			references = append(references, fieldSegments(n.Ident))
+		case *parse.VariableNode:
+			if len(n.Ident) > 1 && n.Ident[0] == "$" {
+				references = append(references, fieldSegments(n.Ident[1:]))
+			}
+		}
+	}
+	walk(tree.Root)
+	return references, nil
+}
+
+// fieldSegments converts the template field identifiers to the key segments
+func fieldSegments(idents []string) []keySegment {
+	segments := make([]keySegment, len(idents))
+	for i, ident := range idents {
+		segments[i] = keySegment{key: ident}
+	}
+	return segments
+}
+
+// renderTemplate renders the template text with text/template, a missing key is an error
+func renderTemplate(data map[string]interface{}, text, leftDelim, rightDelim string) (string, error) {
+	t, err := template.New("parameter").Delims(leftDelim, rightDelim).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+	var buffer bytes.Buffer
+	err = t.Execute(&buffer, data)
+	return buffer.String(), errors.WithStack(err)
+}
+
+// lookup returns the value under the key segments
+func lookup(value interface{}, segments []keySegment) (interface{}, bool) {
+	for _, segment := range segments {
+		if segment.isIndex {
+			list, ok := value.([]interface{})
+			if !ok || segment.index < 0 || segment.index >= len(list) {
+				return nil, false
+			}
+			value = list[segment.index]
+			continue
+		}
+		m, ok := asMap(value)
+		if !ok {
+			return nil, false
+		}
+		value, ok = m[segment.key]
+		if !ok {
+			return nil, false
+		}
+	}
+	return value, true
+}
+
+// stringOf formats the referenced value, the strings are used as they are, the other values as JSON
+func stringOf(value interface{}) string {
+	if s, ok := value.(string); ok {
+		return s
+	}
+	b, err := json.Marshal(value)
+	if err != nil {
+		return ""
+	}
+	return string(b)
+}
+
+// appendSegment returns a new slice of the segments with the segment appended
+func appendSegment(segments []keySegment, segment keySegment) []keySegment {
+	return append(append(make([]keySegment, 0, len(segments)+1), segments...), segment)
+}
+
+// pointerOf returns the JSON pointer of the key segments
+func pointerOf(segments []keySegment) string {
+	var b strings.Builder
+	for _, segment := range segments {
+		b.WriteString("/")
+		if segment.isIndex {
+			b.WriteString(strconv.Itoa(segment.index))
+		} else {
+			b.WriteString(escapePointer(segment.key))
+		}
+	}
+	return b.String()
+}
+
+// keyOf returns the key of the key segments, e.g. 'a.b[0]', see also parseKey
+func keyOf(segments []keySegment) string {
+	var b strings.Builder
+	for _, segment := range segments {
+		if segment.isIndex {
+			b.WriteString(segment.String())
+			continue
+		}
+		if b.Len() > 0 {
+			b.WriteString(".")
+		}
+		b.WriteString(escapeKey(segment.key))
+	}
+	return b.String()
+}
@@ -0,0 +1,72 @@
+package parameters
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParameters_Interpolate(t *testing.T) {
+	params := Parameters{
+		"domain":  "example.com",
+		"port":    8443,
+		"url":     "https://${domain}:${port}/api",
+		"api":     "{{ .url }}/v1",
+		"hosts":   []interface{}{"a.${domain}", "${servers[0].name}"},
+		"servers": []interface{}{map[string]interface{}{"name": "${ports.http}"}},
+		"ports":   Parameters{"http": "${port}"},
+		"copy":    "${ports}",
+		"escaped": "$${domain} {{ `{{` }}",
+		EnvKey:    Parameters{"PS1": "${domain}"},
+	}
+
+	err := params.Interpolate(nil)
+	assert.NoError(t, err)
+	assert.Equal(t, Parameters{
+		"domain":  "example.com",
+		"port":    8443,
+		"url":     "https://example.com:8443/api",
+		"api":     "https://example.com:8443/api/v1",
+		"hosts":   []interface{}{"a.example.com", 8443},
+		"servers": []interface{}{map[string]interface{}{"name": 8443}},
+		"ports":   Parameters{"http": 8443},
+		"copy":    Parameters{"http": 8443},
+		"escaped": "${domain} {{",
+		EnvKey:    Parameters{"PS1": "${domain}"},
+	}, params)
+
+	t.Run("custom render function", func(t *testing.T) {
+		params := Parameters{"a": "{{ .b }}", "b": "x"}
+		err := params.Interpolate(func(data map[string]interface{}, text string) (string, error) {
+			return strings.ToUpper(text) + data["b"].(string), nil
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, Parameters{"a": "{{ .B }}x", "b": "x"}, params)
+	})
+
+	t.Run("custom delimiters", func(t *testing.T) {
+		params := Parameters{"a": "[[ .b ]] {{ .c }}", "b": "[[ .c ]]", "c": "x"}
+		err := params.InterpolateWithDelims("[[", "]]", nil)
+		assert.NoError(t, err)
+		assert.Equal(t, Parameters{"a": "x {{ .c }}", "b": "x", "c": "x"}, params)
+	})
+
+	t.Run("cycle", func(t *testing.T) {
+		params := Parameters{"a": "${b}", "b": "{{ .c.d }}", "c": Parameters{"d": "${a}"}}
+		err := params.Interpolate(nil)
+		assert.EqualError(t, err, "cycle in the parameter references: a -> b -> c.d -> a")
+	})
+
+	t.Run("self reference", func(t *testing.T) {
+		params := Parameters{"a": "x${a}"}
+		err := params.Interpolate(nil)
+		assert.EqualError(t, err, "cycle in the parameter references: a -> a")
+	})
+
+	t.Run("unknown reference", func(t *testing.T) {
+		params := Parameters{"a": Parameters{"b": "${missing}"}}
+		err := params.Interpolate(nil)
+		assert.EqualError(t, err, "can't interpolate 'a.b': unknown parameter 'missing'")
+	})
+}
@@ -0,0 +1,190 @@
+package parameters
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// keySegment is a single map key or list index of a nested key, see also parseKey
+type keySegment struct {
+	key     string
+	index   int // -1 appends to the list
+	isIndex bool
+}
+
+func (s keySegment) String() string {
+	if !s.isIndex {
+		return "." + s.key
+	}
+	if s.index < 0 {
+		return "[]"
+	}
+	return "[" + strconv.Itoa(s.index) + "]"
+}
+
+// parseKey parses a nested key, e.g. 'a.b[2].c', the segments are separated with dots,
+// '[n]' is a list index, '[]' appends to the list, a backslash escapes the next character,
+// e.g. 'annotations.app\.kubernetes\.io/name'
+func parseKey(nestedKey string) ([]keySegment, error) {
+	if len(nestedKey) == 0 {
+		return nil, errors.New("unexpected empty nestedKey")
+	}
+
+	var segments []keySegment
+	var key strings.Builder
+	afterIndex := false // the previous segment is a list index, so no key is expected before '.' or '['
+	endKey := func() error {
+		if key.Len() == 0 {
+			if afterIndex {
+				return nil
+			}
+			return errors.Errorf("invalid key '%s': unexpected empty key", nestedKey)
+		}
+		segments = append(segments, keySegment{key: key.String()})
+		key.Reset()
+		return nil
+	}
+
+	for i := 0; i < len(nestedKey); i++ {
+		switch c := nestedKey[i]; c {
+		case '\\':
+			if i+1 == len(nestedKey) {
+				return nil, errors.Errorf("invalid key '%s': unexpected trailing backslash", nestedKey)
+			}
+			i++
+			key.WriteByte(nestedKey[i])
+		case '.':
+			if err := endKey(); err != nil {
+				return nil, err
+			}
+			afterIndex = false
+		case '[':
+			if err := endKey(); err != nil {
+				return nil, err
+			}
+			end := strings.IndexByte(nestedKey[i:], ']')
+			if end < 0 {
+				return nil, errors.Errorf("invalid key '%s': missing ']'", nestedKey)
+			}
+			indexString := nestedKey[i+1 : i+end]
+			segment := keySegment{index: -1, isIndex: true}
+			if len(indexString) > 0 {
+				index, err := strconv.Atoi(indexString)
+				if err != nil || index < 0 {
+					return nil, errors.Errorf("invalid key '%s': invalid list index '%s'", nestedKey, indexString)
+				}
+				segment.index = index
+			}
+			segments = append(segments, segment)
+			afterIndex = true
+			i += end
+			if i+1 < len(nestedKey) && nestedKey[i+1] != '.' && nestedKey[i+1] != '[' {
+				return nil, errors.Errorf("invalid key '%s': expected '.' or '[' after ']'", nestedKey)
+			}
+		default:
+			key.WriteByte(c)
+		}
+	}
+	if err := endKey(); err != nil {
+		return nil, err
+	}
+	return segments, nil
+}
+
+// setNested sets the value under the key segments and returns the updated current value,
+// the missing maps and lists are created, the lists are padded with nil values if necessary,
+// the Tombstone value removes the map key or the list element
+func setNested(current interface{}, segments []keySegment, value interface{}, path string) (interface{}, error) {
+	if len(segments) == 0 {
+		return value, nil
+	}
+	segment := segments[0]
+	childPath := strings.TrimPrefix(path+segment.String(), ".")
+
+	if !segment.isIndex {
+		var m map[string]interface{}
+		switch c := current.(type) {
+		case nil:
+			created := Parameters{}
+			m, current = created, created
+		case Parameters:
+			m = c
+		case map[string]interface{}:
+			m = c
+		default:
+			return nil, errors.Errorf("key conflict: can't set '%s', '%s' is not a map, it has type: '%T'",
+				childPath, strings.TrimPrefix(path, "."), current)
+		}
+		if len(segments) == 1 && value == Tombstone {
+			delete(m, segment.key)
+			return current, nil
+		}
+		child, err := setNested(m[segment.key], segments[1:], value, childPath)
+		if err != nil {
+			return nil, err
+		}
+		m[segment.key] = child
+		return current, nil
+	}
+
+	var list []interface{}
+	switch c := current.(type) {
+	case nil:
+	case []interface{}:
+		list = c
+	default:
+		return nil, errors.Errorf("key conflict: can't set '%s', '%s' is not a list, it has type: '%T'",
+			childPath, strings.TrimPrefix(path, "."), current)
+	}
+	index := segment.index
+	if len(segments) == 1 && value == Tombstone {
+		if index >= 0 && index < len(list) {
+			list = append(list[:index], list[index+1:]...)
+		}
+		return list, nil
+	}
+	if index < 0 {
+		index = len(list)
+	}
+	for len(list) <= index {
+		list = append(list, nil)
+	}
+	child, err := setNested(list[index], segments[1:], value, childPath)
+	if err != nil {
+		return nil, err
+	}
+	list[index] = child
+	return list, nil
+}
+
+// appendNested sets the value under the nested key, see also parseKey for the key syntax
+func appendNested(parameters *Parameters, nestedKey string, nestedValue interface{}) (*Parameters, error) {
+	if parameters == nil {
+		return nil, errors.New("unexpected nil parameters")
+	}
+	segments, err := parseKey(nestedKey)
+	if err != nil {
+		return parameters, err
+	}
+	if segments[0].isIndex {
+		return nil, errors.Errorf("invalid key '%s': expected a map key first", nestedKey)
+	}
+	_, err = setNested(*parameters, segments, nestedValue, "")
+	if err != nil {
+		return nil, err
+	}
+	return parameters, nil
+}
+
+// Lookup returns the value under the nested key, see also parseKey for the key syntax,
+// false if there is no such value
+func (parameters Parameters) Lookup(nestedKey string) (interface{}, bool, error) {
+	segments, err := parseKey(nestedKey)
+	if err != nil {
+		return nil, false, err
+	}
+	value, ok := lookup(parameters, segments)
+	return value, ok, nil
+}
@@ -0,0 +1,219 @@
+package parameters
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Tombstone is a special value that removes the key when merged or set, e.g. 'key: ~delete' in a configuration file
+const Tombstone = "~delete"
+
+// ListMerge defines how the lists are merged, see also MergeStrategy
+type ListMerge string
+
+const (
+	// ReplaceLists replaces the earlier lists with the later ones
+	ReplaceLists ListMerge = "replace"
+	// AppendLists appends the elements of the later lists to the earlier ones
+	AppendLists ListMerge = "append"
+	// MergeListsByKey merges the map elements with the same value of the key field, e.g. 'name',
+	// and appends the rest of the elements
+	MergeListsByKey ListMerge = "merge-by-key"
+	// DefaultMergeKey is the key field used by MergeListsByKey if none is set
+	DefaultMergeKey = "name"
+)
+
+// MergeStrategy defines how the parameter sets are merged, the maps are always merged recursively,
+// the other values are replaced, see also ParseMergeStrategy and Tombstone
+type MergeStrategy struct {
+	Lists ListMerge
+	// Key is the key field of the list elements used by MergeListsByKey
+	Key string
+	// Origins, if not nil, records the origins of the values in All and FromFiles
+	Origins Origins
+	// Profiles are the names of the profiles selected in the configuration files, see also ProfilesKey
+	Profiles []string
+}
+
+// DefaultMergeStrategy is the merge strategy used if none is set, e.g. by Merge
+var DefaultMergeStrategy = MergeStrategy{Lists: ReplaceLists}
+
+// ParseMergeStrategy parses the merge strategy, one of: 'replace', 'append', 'merge-by-key'
+// or 'merge-by-key=field', the default key field is DefaultMergeKey
+func ParseMergeStrategy(strategy string) (MergeStrategy, error) {
+	lists, key := strategy, ""
+	if i := strings.Index(strategy, "="); i >= 0 {
+		lists, key = strategy[:i], strategy[i+1:]
+	}
+
+	switch ListMerge(lists) {
+	case "", ReplaceLists, AppendLists:
+		if len(key) > 0 {
+			return MergeStrategy{}, errors.Errorf("unexpected key field in the merge strategy: '%s'", strategy)
+		}
+		if len(lists) == 0 {
+			return DefaultMergeStrategy, nil
+		}
+		return MergeStrategy{Lists: ListMerge(lists)}, nil
+	case MergeListsByKey:
+		if len(key) == 0 {
+			key = DefaultMergeKey
+		}
+		return MergeStrategy{Lists: MergeListsByKey, Key: key}, nil
+	default:
+		return MergeStrategy{}, errors.Errorf("unexpected merge strategy: '%s', strategy must be in: '%s'",
+			strategy, []ListMerge{ReplaceLists, AppendLists, MergeListsByKey + "[=field]"})
+	}
+}
+
+func (s MergeStrategy) String() string {
+	if s.Lists == MergeListsByKey {
+		return fmt.Sprintf("%s=%s", s.Lists, s.Key)
+	}
+	return string(s.Lists)
+}
+
+// Merge creates a new parameters from one or more parameter sets with the strategy,
+// the parameter sets are not modified, see also the package level Merge
+func (s MergeStrategy) Merge(parameters ...Parameters) (Parameters, error) {
+	var accumulator = make(Parameters)
+	for _, config := range parameters {
+		err := s.merge(accumulator, config)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return accumulator, nil
+}
+
+// merge merges the src parameters into the dst parameters in place
+func (s MergeStrategy) merge(dst, src map[string]interface{}) error {
+	for key, srcValue := range src {
+		if srcValue == Tombstone {
+			delete(dst, key)
+			continue
+		}
+		value, err := s.mergeValues(dst[key], srcValue, key)
+		if err != nil {
+			return err
+		}
+		dst[key] = value
+	}
+	return nil
+}
+
+// mergeValues returns the merged values, the maps and lists are copied, so the values are not modified
+func (s MergeStrategy) mergeValues(dst, src interface{}, path string) (interface{}, error) {
+	srcMap, isSrcMap := asMap(src)
+	_, isDstMap := asMap(dst)
+	if isSrcMap && isDstMap {
+		merged, m := copyMap(dst)
+		err := s.merge(m, srcMap)
+		return merged, err
+	}
+
+	srcList, isSrcList := src.([]interface{})
+	dstList, isDstList := dst.([]interface{})
+	if !isSrcList || !isDstList {
+		return withoutTombstones(src), nil
+	}
+
+	switch s.Lists {
+	case "", ReplaceLists:
+		return withoutTombstones(src), nil
+	case AppendLists:
+		merged := append(append([]interface{}{}, dstList...), withoutTombstones(srcList).([]interface{})...)
+		return merged, nil
+	case MergeListsByKey:
+		merged := append([]interface{}{}, dstList...)
+		for _, srcElement := range srcList {
+			i := s.indexByKey(merged, srcElement)
+			if i < 0 {
+				merged = append(merged, withoutTombstones(srcElement))
+				continue
+			}
+			value, err := s.mergeValues(merged[i], srcElement, fmt.Sprintf("%s[%d]", path, i))
+			if err != nil {
+				return nil, err
+			}
+			merged[i] = value
+		}
+		return merged, nil
+	default:
+		return nil, errors.Errorf("unexpected list merge strategy for '%s': '%s'", path, s.Lists)
+	}
+}
+
+// indexByKey returns the index of the map element with the same value of the key field, or -1
+func (s MergeStrategy) indexByKey(list []interface{}, element interface{}) int {
+	elementMap, ok := asMap(element)
+	if !ok {
+		return -1
+	}
+	keyValue, ok := elementMap[s.Key]
+	if !ok {
+		return -1
+	}
+	for i, e := range list {
+		if m, ok := asMap(e); ok {
+			if v, ok := m[s.Key]; ok && reflect.DeepEqual(v, keyValue) {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// asMap returns the value as a map, if it is one
+func asMap(value interface{}) (map[string]interface{}, bool) {
+	switch m := value.(type) {
+	case Parameters:
+		return m, true
+	case map[string]interface{}:
+		return m, true
+	default:
+		return nil, false
+	}
+}
+
+// copyMap returns a shallow copy of the map value, of the same type as the original value,
+// and the copied map itself
+func copyMap(value interface{}) (interface{}, map[string]interface{}) {
+	m, _ := asMap(value)
+	copied := make(map[string]interface{}, len(m))
+	for k, v := range m {
+		copied[k] = v
+	}
+	if _, ok := value.(Parameters); ok {
+		return Parameters(copied), copied
+	}
+	return copied, copied
+}
+
+// withoutTombstones returns the value with the tombstones removed, the maps and lists are copied
+func withoutTombstones(value interface{}) interface{} {
+	if _, ok := asMap(value); ok {
+		copied, m := copyMap(value)
+		for k, v := range m {
+			if v == Tombstone {
+				delete(m, k)
+			} else {
+				m[k] = withoutTombstones(v)
+			}
+		}
+		return copied
+	}
+	if list, ok := value.([]interface{}); ok {
+		copied := make([]interface{}, 0, len(list))
+		for _, e := range list {
+			if e != Tombstone {
+				copied = append(copied, withoutTombstones(e))
+			}
+		}
+		return copied
+	}
+	return value
+}
@@ -0,0 +1,154 @@
+package parameters
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseMergeStrategy(t *testing.T) {
+	type test struct {
+		strategy string
+		want     MergeStrategy
+		wantErr  bool
+	}
+
+	tests := []test{
+		{strategy: "", want: DefaultMergeStrategy},
+		{strategy: "replace", want: MergeStrategy{Lists: ReplaceLists}},
+		{strategy: "append", want: MergeStrategy{Lists: AppendLists}},
+		{strategy: "merge-by-key", want: MergeStrategy{Lists: MergeListsByKey, Key: DefaultMergeKey}},
+		{strategy: "merge-by-key=id", want: MergeStrategy{Lists: MergeListsByKey, Key: "id"}},
+		{strategy: "append=id", wantErr: true},
+		{strategy: "unknown", wantErr: true},
+	}
+
+	for i, tt := range tests {
+		t.Run(fmt.Sprintf("[%d] %s", i, tt.strategy), func(t *testing.T) {
+			strategy, err := ParseMergeStrategy(tt.strategy)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, strategy)
+		})
+	}
+}
+
+func TestMergeStrategy_Merge(t *testing.T) {
+	base := func() Parameters {
+		return Parameters{
+			"name":  "base",
+			"debug": true,
+			"ports": []interface{}{80, 443},
+			"containers": []interface{}{
+				map[string]interface{}{"name": "app", "image": "app:1", "port": 8080},
+				map[string]interface{}{"name": "sidecar", "image": "sidecar:1"},
+			},
+			"labels": Parameters{"app": "render", "tier": "backend"},
+		}
+	}
+	override := func() Parameters {
+		return Parameters{
+			"debug": Tombstone,
+			"ports": []interface{}{8443},
+			"containers": []interface{}{
+				map[string]interface{}{"name": "app", "image": "app:2", "port": Tombstone},
+				map[string]interface{}{"name": "proxy", "image": "proxy:1"},
+			},
+			"labels": Parameters{"tier": Tombstone, "env": "prod"},
+		}
+	}
+
+	type test struct {
+		strategy MergeStrategy
+		want     Parameters
+	}
+
+	tests := []test{
+		{
+			strategy: MergeStrategy{Lists: ReplaceLists},
+			want: Parameters{
+				"name":  "base",
+				"ports": []interface{}{8443},
+				"containers": []interface{}{
+					map[string]interface{}{"name": "app", "image": "app:2"},
+					map[string]interface{}{"name": "proxy", "image": "proxy:1"},
+				},
+				"labels": Parameters{"app": "render", "env": "prod"},
+			},
+		},
+		{
+			strategy: MergeStrategy{Lists: AppendLists},
+			want: Parameters{
+				"name":  "base",
+				"ports": []interface{}{80, 443, 8443},
+				"containers": []interface{}{
+					map[string]interface{}{"name": "app", "image": "app:1", "port": 8080},
+					map[string]interface{}{"name": "sidecar", "image": "sidecar:1"},
+					map[string]interface{}{"name": "app", "image": "app:2"},
+					map[string]interface{}{"name": "proxy", "image": "proxy:1"},
+				},
+				"labels": Parameters{"app": "render", "env": "prod"},
+			},
+		},
+		{
+			strategy: MergeStrategy{Lists: MergeListsByKey, Key: DefaultMergeKey},
+			want: Parameters{
+				"name":  "base",
+				"ports": []interface{}{80, 443, 8443},
+				"containers": []interface{}{
+					map[string]interface{}{"name": "app", "image": "app:2"},
+					map[string]interface{}{"name": "sidecar", "image": "sidecar:1"},
+					map[string]interface{}{"name": "proxy", "image": "proxy:1"},
+				},
+				"labels": Parameters{"app": "render", "env": "prod"},
+			},
+		},
+	}
+
+	for i, tt := range tests {
+		t.Run(fmt.Sprintf("[%d] %s", i, tt.strategy), func(t *testing.T) {
+			b, o := base(), override()
+			got, err := tt.strategy.Merge(b, o)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+			assert.Equal(t, base(), b, "unexpected modification of the base parameters")
+			assert.Equal(t, override(), o, "unexpected modification of the override parameters")
+		})
+	}
+}
+
+func TestMerge_ZeroValues(t *testing.T) {
+	got, err := Merge(
+		Parameters{"debug": true, "replicas": 3, "name": "render", "image": "app:1", "labels": Parameters{"tier": "web"}},
+		Parameters{"debug": false, "replicas": 0, "name": "", "image": nil, "labels": Parameters{}},
+	)
+	assert.NoError(t, err)
+	assert.Equal(t, Parameters{
+		"debug":    false,
+		"replicas": 0,
+		"name":     "",
+		"image":    nil,
+		"labels":   Parameters{"tier": "web"},
+	}, got)
+}
+
+func TestParameters_SetVars_Tombstone(t *testing.T) {
+	params := Parameters{
+		"debug": true,
+		"ports": []interface{}{80, 443},
+		"nested": Parameters{
+			"a": 1,
+			"b": 2,
+		},
+	}
+	err := params.SetVars([]string{"debug=~delete", "ports[0]=~delete", "nested.b=~delete", "missing=~delete"})
+	assert.NoError(t, err)
+	assert.Equal(t, Parameters{
+		"ports":  []interface{}{443},
+		"nested": Parameters{"a": 1},
+	}, params)
+}
@@ -0,0 +1,214 @@
+package parameters
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Origin is the source of a single parameter value, see also Origins
+type Origin struct {
+	// Source is e.g. a configuration file path, '--set[0]' (the first --set variable), 'env' or 'base'
+	Source string
+	// Line is the line of the value in the source file, 0 if unknown
+	Line int
+	// Value is the value set by the source
+	Value interface{}
+}
+
+func (o Origin) String() string {
+	if o.Line > 0 {
+		return fmt.Sprintf("%s:%d", o.Source, o.Line)
+	}
+	return o.Source
+}
+
+// Origins maps the JSON pointers of the parameter values, e.g. '/db/port', to the origins of the values,
+// the last origin is the effective one, the earlier ones are shadowed by it, see also MergeStrategy and Track
+type Origins map[string][]Origin
+
+// Track records the source as the origin of all the values in after which are new or different than in before,
+// the root has no origin, it is a no-op for nil origins
+func (o Origins) Track(source string, before, after interface{}) {
+	o.trackLines(source, nil, before, after)
+}
+
+// TrackVars sets the variables one by one with the set function, e.g. Parameters.SetVars, and records
+// the origin of the i-th variable as 'name[i]', e.g. '--set[0]', the variables are set at once for nil origins
+func (o Origins) TrackVars(name string, parameters Parameters, vars []string, set func(vars []string) error) error {
+	if o == nil {
+		return set(vars)
+	}
+	for i, v := range vars {
+		before := parameters.Copy()
+		err := set([]string{v})
+		if err != nil {
+			return err
+		}
+		o.Track(fmt.Sprintf("%s[%d]", name, i), before, parameters)
+	}
+	return nil
+}
+
+// trackLines is the same as Track, but also records the lines of the values, keyed by the JSON pointers
+func (o Origins) trackLines(source string, lines map[string]int, before, after interface{}) {
+	if o == nil {
+		return
+	}
+	o.track(source, lines, "", before, after, true)
+}
+
+func (o Origins) track(source string, lines map[string]int, pointer string, before, after interface{}, found bool) {
+	record := func() {
+		o[pointer] = append(o[pointer], Origin{Source: source, Line: lines[pointer], Value: deepCopy(after)})
+	}
+
+	if afterMap, ok := asMap(after); ok {
+		beforeMap, isMap := asMap(before)
+		if len(pointer) > 0 && (!found || !isMap) {
+			record()
+		}
+		for key, value := range afterMap {
+			beforeValue, ok := beforeMap[key]
+			o.track(source, lines, pointer+"/"+escapePointer(key), beforeValue, value, ok)
+		}
+		return
+	}
+	if afterList, ok := after.([]interface{}); ok {
+		beforeList, isList := before.([]interface{})
+		if !found || !isList {
+			record()
+		}
+		for i, value := range afterList {
+			var beforeValue interface{}
+			if i < len(beforeList) {
+				beforeValue = beforeList[i]
+			}
+			o.track(source, lines, pointer+"/"+strconv.Itoa(i), beforeValue, value, i < len(beforeList))
+		}
+		return
+	}
+	if !found || !reflect.DeepEqual(before, after) {
+		record()
+	}
+}
+
+// Of returns the effective origin of the value under the JSON pointer, or the origin of the closest parent
+// if the value has no origin, e.g. a missing key, an empty origin is returned if nothing is found
+func (o Origins) Of(pointer string) Origin {
+	for {
+		if origins := o[pointer]; len(origins) > 0 {
+			return origins[len(origins)-1]
+		}
+		i := strings.LastIndex(pointer, "/")
+		if i < 0 {
+			return Origin{}
+		}
+		pointer = pointer[:i]
+	}
+}
+
+// Explain writes all the values of the parameters with their origins, one value per line, e.g.
+// 'db.host: "db.prod"  # prod.yaml:3, overrides "localhost" from base.yaml:2',
+// the keys use the same syntax as the variables, see also SetVars
+func (o Origins) Explain(w io.Writer, parameters Parameters) error {
+	return o.explain(w, "", "", parameters)
+}
+
+func (o Origins) explain(w io.Writer, pointer, key string, value interface{}) error {
+	if m, ok := asMap(value); ok && (len(m) > 0 || len(pointer) == 0) {
+		keys := make([]string, 0, len(m))
+		for k := range m {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			childKey := escapeKey(k)
+			if len(key) > 0 {
+				childKey = key + "." + childKey
+			}
+			err := o.explain(w, pointer+"/"+escapePointer(k), childKey, m[k])
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if list, ok := value.([]interface{}); ok && len(list) > 0 {
+		for i, e := range list {
+			err := o.explain(w, pointer+"/"+strconv.Itoa(i), fmt.Sprintf("%s[%d]", key, i), e)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	line := fmt.Sprintf("%s: %s", key, explainValue(value))
+	if origin := o.Of(pointer); len(origin.Source) > 0 {
+		line += "  # " + origin.String()
+	}
+	origins := o[pointer]
+	for i := len(origins) - 2; i >= 0; i-- {
+		line += fmt.Sprintf(", overrides %s from %s", explainValue(origins[i].Value), origins[i])
+	}
+	_, err := fmt.Fprintln(w, line)
+	return err
+}
+
+// explainValue formats the value as JSON
+func explainValue(value interface{}) string {
+	var b strings.Builder
+	encoder := json.NewEncoder(&b)
+	encoder.SetEscapeHTML(false)
+	err := encoder.Encode(value)
+	if err != nil {
+		return fmt.Sprintf("%v", value)
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// escapePointer escapes a JSON pointer reference token, see RFC 6901
+func escapePointer(token string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(token, "~", "~0"), "/", "~1")
+}
+
+// escapeKey escapes the special characters of a key segment, see also parseKey
+func escapeKey(key string) string {
+	var b strings.Builder
+	for _, c := range key {
+		if c == '.' || c == '[' || c == ']' || c == '\\' {
+			b.WriteRune('\\')
+		}
+		b.WriteRune(c)
+	}
+	return b.String()
+}
+
+// deepCopy returns a copy of the value, the maps and lists are copied recursively
+func deepCopy(value interface{}) interface{} {
+	if _, ok := asMap(value); ok {
+		copied, m := copyMap(value)
+		for k, v := range m {
+			m[k] = deepCopy(v)
+		}
+		return copied
+	}
+	if list, ok := value.([]interface{}); ok {
+		copied := make([]interface{}, len(list))
+		for i, e := range list {
+			copied[i] = deepCopy(e)
+		}
+		return copied
+	}
+	return value
+}
+
+// Copy returns a deep copy of the parameters, the maps and lists are copied recursively
+func (parameters Parameters) Copy() Parameters {
+	return deepCopy(parameters).(Parameters)
+}
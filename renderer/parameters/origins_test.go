@@ -0,0 +1,62 @@
+package parameters
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOrigins_Track(t *testing.T) {
+	origins := Origins{}
+	first := Parameters{"a": "x", "list": []interface{}{1, 2}, "nested": Parameters{"b": 1}}
+	second := first.Copy()
+	second["a"] = "y"
+	second["list"] = append(second["list"].([]interface{}), 3)
+	second["nested"].(Parameters)["c"] = 2
+
+	origins.Track("first", nil, first)
+	origins.Track("second", first, second)
+
+	assert.Equal(t, Origins{
+		"/a":        {{Source: "first", Value: "x"}, {Source: "second", Value: "y"}},
+		"/list":     {{Source: "first", Value: []interface{}{1, 2}}},
+		"/list/0":   {{Source: "first", Value: 1}},
+		"/list/1":   {{Source: "first", Value: 2}},
+		"/list/2":   {{Source: "second", Value: 3}},
+		"/nested":   {{Source: "first", Value: Parameters{"b": 1}}},
+		"/nested/b": {{Source: "first", Value: 1}},
+		"/nested/c": {{Source: "second", Value: 2}},
+	}, origins)
+	assert.Equal(t, "second", origins.Of("/nested/c/missing").String())
+	assert.Equal(t, "", origins.Of("/missing").String())
+	assert.Equal(t, Parameters{"b": 1}, first["nested"], "unexpected modification of the copied parameters")
+}
+
+func TestOrigins_Explain(t *testing.T) {
+	dir := tempDir(t)
+
+	basePath := writeFile(t, dir, "base.yaml", "db:\n  host: localhost\n  port: 5432\nhosts:\n  - a\n  - b\n")
+	prodPath := writeFile(t, dir, "prod.json", "{\n  \"db\": {\n    \"host\": \"db.prod\"\n  },\n  \"labels\": {\"app.kubernetes.io/name\": \"x\"}\n}")
+	envPath := writeFile(t, dir, "prod.env", "# comment\nTIER=backend\n")
+
+	origins := Origins{}
+	strategy := DefaultMergeStrategy
+	strategy.Origins = origins
+	params, err := strategy.All([]string{basePath, prodPath, envPath}, []string{"db.port=6543", "hosts[]=c"})
+	assert.NoError(t, err)
+	params[RootKey] = "/root"
+
+	var buffer bytes.Buffer
+	err = origins.Explain(&buffer, params)
+	assert.NoError(t, err)
+	assert.Equal(t, `TIER: "backend"  # `+envPath+`:2
+db.host: "db.prod"  # `+prodPath+`:3, overrides "localhost" from `+basePath+`:2
+db.port: 6543  # --set[0], overrides 5432 from `+basePath+`:3
+hosts[0]: "a"  # `+basePath+`:5
+hosts[1]: "b"  # `+basePath+`:6
+hosts[2]: "c"  # --set[1]
+labels.app\.kubernetes\.io/name: "x"  # `+prodPath+`:5
+root: "/root"  # base
+`, buffer.String())
+}
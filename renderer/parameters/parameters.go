@@ -1,15 +1,20 @@
 package parameters
 
 import (
+	"encoding/json"
+	"fmt"
 	"io/ioutil"
-	"reflect"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
 	"strings"
 
 	"github.com/VirtusLab/go-extended/pkg/files"
 	"github.com/VirtusLab/go-extended/pkg/matcher"
 
 	"github.com/ghodss/yaml"
-	"github.com/imdario/mergo"
 	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
 )
@@ -17,6 +22,10 @@ import (
 const (
 	// RootKey is an special configuration key key used by e.g. the Base and Root functions
 	RootKey = "root"
+	// EnvKey is the configuration key with all the environment variables, if exposed with Parameters.SetEnv
+	EnvKey = "env"
+	// EnvNestingSeparator separates the nested keys in the environment variable names, see also FromEnv
+	EnvNestingSeparator = "__"
 )
 
 var (
@@ -27,44 +36,52 @@ var (
 // Parameters is a map used to render the templates with
 type Parameters map[string]interface{}
 
-// Validate checks the internal state and returns error if necessary
-func (parameters Parameters) Validate() error {
-	// TODO(pprazak): could/should we do some validation here?
-	return nil
-}
-
-// Merge creates a new parameters from one or more parameter sets, to be used with other helper functions
+// Merge creates a new parameters from one or more parameter sets, to be used with other helper functions,
+// the later values override the earlier ones, the maps are merged and the lists replaced, see also MergeStrategy
 func Merge(parameters ...Parameters) (Parameters, error) {
-	var accumulator = make(Parameters)
-	for _, config := range parameters {
-		err := merge(&accumulator, config)
-		if err != nil {
-			return nil, err
-		}
-	}
-
-	return accumulator, nil
+	return DefaultMergeStrategy.Merge(parameters...)
 }
 
 // All creates a configuration from one or more configuration file paths
-// and one or more extra variables in addition to base configuration
-func All(configPaths, vars []string) (Parameters, error) {
+// and one or more extra variables in addition to base configuration,
+// the optional extra sources (e.g. FromEnv) are merged after the files, and the variables
+// are set on top of the result (see SetVars), so they can e.g. override a single list element,
+// the precedence from the lowest is: base, files, extra sources (in order), variables
+func All(configPaths, vars []string, sources ...Parameters) (Parameters, error) {
+	return DefaultMergeStrategy.All(configPaths, vars, sources...)
+}
+
+// All creates a configuration the same way as All, but merges the files and the sources with the strategy
+func (s MergeStrategy) All(configPaths, vars []string, sources ...Parameters) (Parameters, error) {
 	baseConfig, err := Base()
 	if err != nil {
 		return nil, errors.Wrap(err, "can't create base configuration")
 	}
 
-	filesConfig, err := FromFiles(configPaths)
+	filesConfig, err := s.FromFiles(configPaths)
 	if err != nil {
 		return nil, errors.Wrap(err, "can't parse configuration files")
 	}
 
-	varsConfig, err := FromVars(vars)
+	s.Origins.Track("base", nil, baseConfig)
+	all, err := s.Merge(baseConfig, filesConfig)
 	if err != nil {
-		return nil, errors.Wrap(err, "can't parse extra configuration variables")
+		return nil, err
+	}
+	for i, source := range sources {
+		before := all
+		all, err = s.Merge(all, source)
+		if err != nil {
+			return nil, err
+		}
+		s.Origins.Track(fmt.Sprintf("source[%d]", i), before, all)
 	}
 
-	return Merge(baseConfig, filesConfig, varsConfig)
+	err = s.Origins.TrackVars("--set", all, vars, all.SetVars)
+	if err != nil {
+		return nil, errors.Wrap(err, "can't parse extra configuration variables")
+	}
+	return all, nil
 }
 
 // Base creates a basic configuration required for some of the functions, it is recommended to use it
@@ -80,105 +97,364 @@ func Base() (Parameters, error) {
 	return c, nil
 }
 
-// FromFiles creates a configuration from one or more configuration file paths
+// FromFiles creates a configuration from one or more configuration file paths,
+// the format of each file is detected from the extension or set with a 'format:path' prefix, see also FormatOf
 func FromFiles(configPaths []string) (Parameters, error) {
+	return DefaultMergeStrategy.FromFiles(configPaths)
+}
+
+// FromFiles creates a configuration the same way as FromFiles, but merges the files with the strategy,
+// the imported files of each file are merged before the file (see also ImportsKey), and the selected profiles
+// of each file are merged over the rest of the file (see also ProfilesKey)
+func (s MergeStrategy) FromFiles(configPaths []string) (Parameters, error) {
 	var accumulator = make(Parameters)
+	defined := make(map[string]bool)
 	for i, configPath := range configPaths {
 		logrus.Debugf("Reading configuration file [%d]: %v", i, configPath)
-		err := files.CheckNotEmptyAndExists(configPath)
+		err := s.fromFile(accumulator, defined, configPath, nil)
 		if err != nil {
-			logrus.Errorf("Can't find the configuration file '%s': %v", configPath, err)
-			return nil, errors.WithStack(err)
+			return nil, err
+		}
+	}
+	for _, profile := range s.Profiles {
+		if !defined[profile] {
+			return nil, errors.Errorf("unknown profile '%s', it is not defined in any of the configuration files", profile)
 		}
-		b, err := ioutil.ReadFile(configPath)
+	}
+	logrus.Debugf("Parameters from files: %v", accumulator)
+
+	return accumulator, nil
+}
+
+// fromFile merges the configuration file with its imports and profiles into the accumulator,
+// the defined profiles are collected, the stack holds the importing files to detect the cycles
+func (s MergeStrategy) fromFile(accumulator Parameters, defined map[string]bool, configPath string, stack []string) error {
+	format, configPath, b, config, err := readConfig(configPath)
+	if err != nil {
+		return err
+	}
+
+	imports, err := importsOf(configPath, config)
+	if err != nil {
+		return err
+	}
+	if len(imports) > 0 {
+		stack, err = pushImport(stack, configPath)
 		if err != nil {
-			logrus.Errorf("Can't open the configuration file '%s': %v", configPath, err)
-			return nil, errors.WithStack(err)
+			return err
 		}
-		var config map[string]interface{}
-		err = yaml.Unmarshal(b, &config)
+	}
+	for _, imported := range imports {
+		logrus.Debugf("Importing configuration file '%s' from '%s'", imported, configPath)
+		err = s.fromFile(accumulator, defined, imported, stack)
 		if err != nil {
-			logrus.Errorf("Can't parse the configuration file '%s': %v", configPath, err)
-			return nil, errors.WithStack(err)
+			return err
+		}
+	}
+
+	profiles, names, err := s.selectProfiles(config)
+	if err != nil {
+		return errors.Wrapf(err, "can't select the profiles of the configuration file '%s'", configPath)
+	}
+	for _, name := range names {
+		defined[name] = true
+	}
+
+	var lines map[string]int
+	if s.Origins != nil {
+		lines = lineNumbers(format, b)
+	}
+	// the selected profiles are merged one by one, so the values they override are tracked
+	for _, p := range append([]profile{{values: config}}, profiles...) {
+		var before Parameters
+		layerLines := lines
+		if s.Origins != nil {
+			before = accumulator.Copy()
+		}
+		if len(p.name) > 0 {
+			logrus.Debugf("Merging the profile '%s' of the configuration file '%s'", p.name, configPath)
+			layerLines = profileLines(lines, p.name)
 		}
-		err = merge(&accumulator, config)
+		err = s.merge(accumulator, p.values)
 		if err != nil {
-			return nil, err
+			return err
 		}
+		s.Origins.trackLines(configPath, layerLines, before, accumulator)
 	}
-	logrus.Debugf("Parameters from files: %v", accumulator)
+	return nil
+}
 
-	return accumulator, nil
+// readConfig reads and parses the configuration file, the format prefix is stripped from the returned path
+func readConfig(configPath string) (Format, string, []byte, map[string]interface{}, error) {
+	format, configPath := FormatOf(configPath)
+	err := files.CheckNotEmptyAndExists(configPath)
+	if err != nil {
+		logrus.Errorf("Can't find the configuration file '%s': %v", configPath, err)
+		return format, configPath, nil, nil, errors.WithStack(err)
+	}
+	b, err := ioutil.ReadFile(configPath)
+	if err != nil {
+		logrus.Errorf("Can't open the configuration file '%s': %v", configPath, err)
+		return format, configPath, nil, nil, errors.WithStack(err)
+	}
+	config, err := unmarshal(format, b)
+	if err != nil {
+		logrus.Errorf("Can't parse the configuration file '%s': %v", configPath, err)
+		return format, configPath, nil, nil, errors.Wrapf(err, "can't parse the %s configuration file '%s'", format, configPath)
+	}
+	return format, configPath, b, config, nil
 }
 
-// FromVars creates a configuration from one or more extra variables (key=value), see also VarArgRegexp
+// FromVars creates a configuration from one or more extra variables (key=value), see also VarArgRegexp and SetVars
 func FromVars(extraParams []string) (Parameters, error) {
-	var config = &Parameters{}
+	config := Parameters{}
+	err := config.SetVars(extraParams)
+	if err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+// FromStringVars creates a configuration from one or more extra variables (key=value) with string values,
+// see also SetStringVars
+func FromStringVars(extraParams []string) (Parameters, error) {
+	config := Parameters{}
+	err := config.SetStringVars(extraParams)
+	if err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+// FromJSONVars creates a configuration from one or more extra variables (key=json) with JSON values,
+// see also SetJSONVars
+func FromJSONVars(extraParams []string) (Parameters, error) {
+	config := Parameters{}
+	err := config.SetJSONVars(extraParams)
+	if err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+// FromFileVars creates a configuration from one or more extra variables (key=path) with the file contents,
+// see also SetFileVars
+func FromFileVars(root string, extraParams []string) (Parameters, error) {
+	config := Parameters{}
+	err := config.SetFileVars(root, extraParams)
+	if err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+// SetVars sets the values of one or more extra variables (key=value) in place, see also VarArgRegexp,
+// the types of the values are inferred the way YAML does it for scalars, e.g. '3' is a number,
+// 'true' is a boolean and 'null' is nil, '{a,b}' is a list, a quoted value is always a string.
+// The keys are nested with dots, '[n]' sets a list element, '[]' appends to a list
+// and a backslash escapes a dot, e.g. 'a.b[2]=x', 'a.b[]=x' or 'labels.app\.kubernetes\.io/name=x'
+func (parameters Parameters) SetVars(extraParams []string) error {
+	return parameters.setVars(extraParams, func(raw string) (interface{}, error) {
+		value := strings.Trim(raw, `"'`)
+		if value != raw {
+			return value, nil
+		}
+		return inferValue(value), nil
+	})
+}
+
+// SetStringVars sets the values of one or more extra variables (key=value) in place as strings, see also SetVars
+func (parameters Parameters) SetStringVars(extraParams []string) error {
+	return parameters.setVars(extraParams, func(raw string) (interface{}, error) {
+		return strings.Trim(raw, `"'`), nil
+	})
+}
+
+// SetJSONVars sets the values of one or more extra variables (key=json) in place
+// with the parsed JSON values, e.g. 'key={"a":[1,2]}', see also SetVars
+func (parameters Parameters) SetJSONVars(extraParams []string) error {
+	return parameters.setVars(extraParams, func(raw string) (interface{}, error) {
+		var value interface{}
+		err := json.Unmarshal([]byte(raw), &value)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid JSON value: '%s'", raw)
+		}
+		return value, nil
+	})
+}
+
+// SetFileVars sets the contents of the files of one or more extra variables (key=path) in place,
+// a relative path is relative to the given root directory (see also RootKey), the content is a string
+// unless the path has a '@yaml:' or '@json:' prefix, e.g. 'key=@yaml:values.yaml', see also SetVars
+func (parameters Parameters) SetFileVars(root string, extraParams []string) error {
+	return parameters.setVars(extraParams, func(raw string) (interface{}, error) {
+		var format Format
+		for _, f := range []Format{YAMLFormat, JSONFormat} {
+			if prefix := "@" + string(f) + ":"; strings.HasPrefix(raw, prefix) {
+				format = f
+				raw = strings.TrimPrefix(raw, prefix)
+			}
+		}
+		filePath := raw
+		if !filepath.IsAbs(filePath) {
+			filePath = filepath.Join(root, filePath)
+		}
+
+		b, err := ioutil.ReadFile(filePath)
+		if err != nil {
+			return nil, errors.Wrapf(err, "can't read the file: '%s'", filePath)
+		}
+		var value interface{}
+		switch format {
+		case YAMLFormat:
+			err = yaml.Unmarshal(b, &value)
+		case JSONFormat:
+			err = json.Unmarshal(b, &value)
+		default:
+			return string(b), nil
+		}
+		if err != nil {
+			return nil, errors.Wrapf(err, "can't parse the %s file: '%s'", format, filePath)
+		}
+		return value, nil
+	})
+}
+
+func (parameters Parameters) setVars(extraParams []string, parse func(raw string) (interface{}, error)) error {
+	if len(extraParams) == 0 {
+		return nil
+	}
 	for _, v := range extraParams {
 		groups, ok := VarArgRegexp.MatchGroups(v)
 		if !ok {
 			logrus.Errorf("Expected a valid extra parameter: '%s'", v)
-			return nil, errors.Errorf("invalid parameter: '%s'", v)
+			return errors.Errorf("invalid parameter: '%s'", v)
 		}
 		name := groups["name"]
-		value := strings.Trim(groups["value"], `"'`)
-		logrus.Debugf("Extra var: %s=%s", name, value)
-		isNested := strings.Contains(name, ".")
-		if isNested {
+		value, err := parse(groups["value"])
+		if err != nil {
+			return errors.Wrapf(err, "invalid parameter: '%s'", v)
+		}
+		logrus.Debugf("Extra var: %s=%v (%T)", name, value, value)
+		if strings.ContainsAny(name, ".[") {
 			logrus.Debugf("Extra var key is nested: %s", name)
-			var err error
-			config, err = appendNested(config, name, value)
-			if err != nil {
-				return nil, err
-			}
-		} else {
-			(*config)[name] = value
+		}
+		_, err = appendNested(&parameters, name, value)
+		if err != nil {
+			return errors.Wrapf(err, "invalid parameter: '%s'", v)
 		}
 	}
 
-	logrus.Debugf("Parameters from vars: %v", *config)
-	return *config, nil
+	logrus.Debugf("Parameters from vars: %v", parameters)
+	return nil
 }
 
-func appendNested(parameters *Parameters, nestedKey string, nestedValue interface{}) (*Parameters, error) {
-	if parameters == nil {
-		return nil, errors.New("unexpected nil parameters")
+var (
+	intRegexp   = regexp.MustCompile(`^[-+]?(0|[1-9][0-9]*)$`)
+	floatRegexp = regexp.MustCompile(`^[-+]?([0-9]+\.[0-9]*|\.[0-9]+|[0-9]+)([eE][-+]?[0-9]+)?$`)
+	// leadingZeroRegexp matches the numbers with leading zeros, kept as strings, e.g. zip codes
+	leadingZeroRegexp = regexp.MustCompile(`^[-+]?0[0-9]`)
+)
+
+// inferValue returns the value of the YAML-style scalar, an integer with leading zeros (e.g. '007') is a string,
+// '{a,b}' is a list of the inferred values
+func inferValue(value string) interface{} {
+	switch value {
+	case "true", "True", "TRUE":
+		return true
+	case "false", "False", "FALSE":
+		return false
+	case "null", "Null", "NULL", "~":
+		return nil
+	}
+	if intRegexp.MatchString(value) {
+		if i, err := strconv.ParseInt(value, 10, 64); err == nil {
+			return i
+		}
+	}
+	if floatRegexp.MatchString(value) && !leadingZeroRegexp.MatchString(value) {
+		if f, err := strconv.ParseFloat(value, 64); err == nil {
+			return f
+		}
 	}
-	if len(nestedKey) == 0 {
-		return parameters, errors.New("unexpected empty nestedKey")
+	if strings.HasPrefix(value, "{") && strings.HasSuffix(value, "}") {
+		list := make([]interface{}, 0)
+		inner := strings.TrimSpace(value[1 : len(value)-1])
+		if len(inner) == 0 {
+			return list
+		}
+		for _, item := range strings.Split(inner, ",") {
+			list = append(list, inferValue(strings.TrimSpace(item)))
+		}
+		return list
 	}
-	keys := strings.Split(nestedKey, ".")
-	lastIndex := len(keys) - 1
+	return value
+}
+
+// FromEnv creates a configuration from the environment variables with the given prefix,
+// the prefix is trimmed and the rest of the name is lower-cased and nested on the EnvNestingSeparator,
+// e.g. with the prefix 'RENDER_' the variable 'RENDER_DB__HOST=x' becomes '.db.host', see also Parameters.SetEnv
+func FromEnv(prefix string) (Parameters, error) {
+	return fromEnviron(prefix, os.Environ())
+}
 
-	var current = parameters
-	for i, key := range keys {
-		// assign nested value to the last key
-		if i == lastIndex {
-			(*current)[key] = nestedValue
+func fromEnviron(prefix string, environ []string) (Parameters, error) {
+	sort.Strings(environ)
+	config := Parameters{}
+	for _, v := range environ {
+		i := strings.Index(v, "=")
+		if i <= 0 {
 			continue
 		}
-		// get or create value for current key[i]
-		value, ok := (*current)[key]
-		if !ok {
-			(*current)[key] = Parameters{}
+		name, value := v[:i], v[i+1:]
+		if len(prefix) == 0 || !strings.HasPrefix(name, prefix) {
+			continue
+		}
+
+		// each segment is a single map key, the --set key syntax (e.g. '.' or '[0]') is not parsed
+		keys := strings.Split(strings.ToLower(strings.TrimPrefix(name, prefix)), EnvNestingSeparator)
+		segments := make([]keySegment, 0, len(keys))
+		for _, key := range keys {
+			if len(key) == 0 {
+				segments = nil
+				break
+			}
+			segments = append(segments, keySegment{key: key})
+		}
+		if len(segments) == 0 {
+			logrus.Warnf("Skipping the environment variable with an invalid name: '%s'", name)
+			continue
 		}
-		// get value as a map
-		newCurrent, ok := (*current)[key].(Parameters)
-		if value != nil && !ok {
-			return nil, errors.Errorf(
-				"key conflict: key '%s' already exists and is not a map, it has type: '%s'",
-				key, reflect.TypeOf(value))
+		logrus.Debugf("Environment var: %s -> .%s", name, keyOf(segments))
+
+		_, err := setNested(config, segments, value, "")
+		if err != nil {
+			return nil, errors.Wrapf(err, "can't use the environment variable '%s'", name)
 		}
-		// assign nested map as current
-		current = &newCurrent
 	}
-	return parameters, nil
+
+	logrus.Tracef("Parameters from the environment: %v", config)
+	return config, nil
 }
 
-func merge(dst *Parameters, src Parameters) error {
-	err := mergo.Merge(dst, src, mergo.WithOverride)
-	if err != nil {
-		return errors.WithStack(err)
+// SetEnv sets all the environment variables by their original names under EnvKey, e.g. '.env.HOME',
+// it fails if the key is already set, so a parameter with the same name is never overridden
+func (parameters Parameters) SetEnv() error {
+	return parameters.setEnviron(os.Environ())
+}
+
+func (parameters Parameters) setEnviron(environ []string) error {
+	if _, ok := parameters[EnvKey]; ok {
+		return errors.Errorf("can't set the environment variables, the key '%s' is already set", EnvKey)
+	}
+	env := make(Parameters, len(environ))
+	for _, v := range environ {
+		i := strings.Index(v, "=")
+		if i <= 0 {
+			continue
+		}
+		env[v[:i]] = v[i+1:]
 	}
+	parameters[EnvKey] = env
 	return nil
 }
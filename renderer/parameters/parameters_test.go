@@ -3,9 +3,14 @@ package parameters
 import (
 	"encoding/json"
 	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/sirupsen/logrus"
+	logtest "github.com/sirupsen/logrus/hooks/test"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -223,6 +228,89 @@ func TestWithVars(t *testing.T) {
 		t.Run(fmt.Sprintf("[%d] %s", i, tt.name), func(t *testing.T) { tt.f(tt) })
 	}
 
+	t.Run("typed values", func(t *testing.T) {
+		vars := []string{
+			"int=3",
+			"negative=-12",
+			"float=1.5",
+			"exponent=1e3",
+			"zip=007",
+			"yes=true",
+			"no=False",
+			"nothing=null",
+			"quoted='3'",
+			"version=1.2.3",
+			"list={a, 2, true}",
+			"empty={}",
+		}
+		want := Parameters{
+			"int":      int64(3),
+			"negative": int64(-12),
+			"float":    1.5,
+			"exponent": float64(1000),
+			"zip":      "007",
+			"yes":      true,
+			"no":       false,
+			"nothing":  nil,
+			"quoted":   "3",
+			"version":  "1.2.3",
+			"list":     []interface{}{"a", int64(2), true},
+			"empty":    []interface{}{},
+		}
+
+		got, err := FromVars(vars)
+		assert.NoError(t, err)
+		assert.Equal(t, want, got)
+	})
+
+	t.Run("string values", func(t *testing.T) {
+		got, err := FromStringVars([]string{"int=3", "yes=true", "a.list={a,b}"})
+		assert.NoError(t, err)
+		assert.EqualValues(t, Parameters{
+			"int": "3",
+			"yes": "true",
+			"a":   Parameters{"list": "{a,b}"},
+		}, got)
+	})
+
+	t.Run("JSON values", func(t *testing.T) {
+		got, err := FromJSONVars([]string{`key={"a":[1,2]}`, `nested.key="text"`})
+		assert.NoError(t, err)
+		assert.EqualValues(t, Parameters{
+			"key":    map[string]interface{}{"a": []interface{}{float64(1), float64(2)}},
+			"nested": Parameters{"key": "text"},
+		}, got)
+
+		_, err = FromJSONVars([]string{`key={"a":`})
+		assert.EqualError(t, err, `invalid parameter: 'key={"a":': invalid JSON value: '{"a":': unexpected end of JSON input`)
+	})
+
+	t.Run("file values", func(t *testing.T) {
+		dir := tempDir(t)
+		for name, content := range map[string]string{
+			"cert.pem":    "-----BEGIN CERTIFICATE-----\n",
+			"values.yaml": "list:\n  - a\n  - b\n",
+			"values.json": `{"enabled": true}`,
+		} {
+			writeFile(t, dir, name, content)
+		}
+
+		got, err := FromFileVars(dir, []string{
+			"tls.crt=cert.pem",
+			"yaml=@yaml:values.yaml",
+			"json=@json:" + filepath.Join(dir, "values.json"),
+		})
+		assert.NoError(t, err)
+		assert.EqualValues(t, Parameters{
+			"tls":  Parameters{"crt": "-----BEGIN CERTIFICATE-----\n"},
+			"yaml": map[string]interface{}{"list": []interface{}{"a", "b"}},
+			"json": map[string]interface{}{"enabled": true},
+		}, got)
+
+		_, err = FromFileVars(dir, []string{"missing=missing.txt"})
+		assert.Error(t, err)
+	})
+
 	t.Run("with spaces", func(t *testing.T) {
 		vars := []string{
 			`first="a value"`,
@@ -241,6 +329,26 @@ func TestWithVars(t *testing.T) {
 	})
 }
 
+func TestParameters_SetVars_Log(t *testing.T) {
+	logrus.SetLevel(logrus.DebugLevel)
+	hook := logtest.NewGlobal()
+	defer hook.Reset()
+
+	params := Parameters{"a": 1}
+	assert.NoError(t, params.SetJSONVars(nil))
+	assert.NoError(t, params.SetStringVars(nil))
+	assert.Empty(t, hook.AllEntries(), "nothing should be logged without vars")
+
+	assert.NoError(t, params.SetVars([]string{"b=2"}))
+	var logged int
+	for _, entry := range hook.AllEntries() {
+		if strings.HasPrefix(entry.Message, "Parameters from vars") {
+			logged++
+		}
+	}
+	assert.Equal(t, 1, logged)
+}
+
 func TestAppendNested(t *testing.T) {
 	type args struct {
 		key        string
@@ -322,7 +430,7 @@ func TestAppendNested(t *testing.T) {
 			},
 			f:       standard,
 			want:    nil,
-			wantErr: "key conflict: key 'key' already exists and is not a map, it has type: 'string'",
+			wantErr: "key conflict: can't set 'key.nested', 'key' is not a map, it has type: 'string'",
 		}, {
 			name: "empty with double nested nil",
 			args: args{
@@ -338,6 +446,122 @@ func TestAppendNested(t *testing.T) {
 					},
 				},
 			},
+		}, {
+			name: "list index",
+			args: args{
+				key:        "a.things[1]",
+				value:      "x",
+				parameters: &Parameters{"a": map[string]interface{}{"things": []interface{}{"first", "second"}}},
+			},
+			f: standard,
+			want: &Parameters{
+				"a": map[string]interface{}{"things": []interface{}{"first", "x"}},
+			},
+		}, {
+			name: "list index padded",
+			args: args{
+				key:        "things[2]",
+				value:      "x",
+				parameters: &Parameters{},
+			},
+			f: standard,
+			want: &Parameters{
+				"things": []interface{}{nil, nil, "x"},
+			},
+		}, {
+			name: "list append",
+			args: args{
+				key:        "things[]",
+				value:      "x",
+				parameters: &Parameters{"things": []interface{}{"first"}},
+			},
+			f: standard,
+			want: &Parameters{
+				"things": []interface{}{"first", "x"},
+			},
+		}, {
+			name: "map in list",
+			args: args{
+				key:        "things[0].name",
+				value:      "x",
+				parameters: &Parameters{"things": []interface{}{map[string]interface{}{"name": "first", "id": 1}}},
+			},
+			f: standard,
+			want: &Parameters{
+				"things": []interface{}{map[string]interface{}{"name": "x", "id": 1}},
+			},
+		}, {
+			name: "nested lists",
+			args: args{
+				key:        "matrix[1][]",
+				value:      "x",
+				parameters: &Parameters{},
+			},
+			f: standard,
+			want: &Parameters{
+				"matrix": []interface{}{nil, []interface{}{"x"}},
+			},
+		}, {
+			name: "escaped dots",
+			args: args{
+				key:        `annotations.app\.kubernetes\.io/name`,
+				value:      "x",
+				parameters: &Parameters{},
+			},
+			f: standard,
+			want: &Parameters{
+				"annotations": Parameters{"app.kubernetes.io/name": "x"},
+			},
+		}, {
+			name: "list conflict",
+			args: args{
+				key:        "things[0]",
+				value:      "x",
+				parameters: &Parameters{"things": "avalue"},
+			},
+			f:       standard,
+			want:    nil,
+			wantErr: "key conflict: can't set 'things[0]', 'things' is not a list, it has type: 'string'",
+		}, {
+			name: "map in list conflict",
+			args: args{
+				key:        "things[0].name",
+				value:      "x",
+				parameters: &Parameters{"things": []interface{}{"first"}},
+			},
+			f:       standard,
+			want:    nil,
+			wantErr: "key conflict: can't set 'things[0].name', 'things[0]' is not a map, it has type: 'string'",
+		}, {
+			name: "invalid index",
+			args: args{
+				key:        "things[-1]",
+				value:      "x",
+				parameters: &Parameters{},
+			},
+			f:       standard,
+			want:    &Parameters{},
+			wantErr: "invalid key 'things[-1]': invalid list index '-1'",
+		}, {
+			name: "missing bracket",
+			args: args{
+				key:        "things[0",
+				value:      "x",
+				parameters: &Parameters{},
+			},
+			f:       standard,
+			want:    &Parameters{},
+			wantErr: "invalid key 'things[0': missing ']'",
+		}, {
+			name: "empty key",
+			args: args{
+				key:        "a..b",
+				value:      "x",
+				parameters: &Parameters{},
+			},
+			f:       standard,
+			want:    &Parameters{},
+			wantErr: "invalid key 'a..b': unexpected empty key",
 		},
 	}
 
@@ -347,3 +571,128 @@ func TestAppendNested(t *testing.T) {
 		t.Run(fmt.Sprintf("[%d] %s", i, tt.name), func(t *testing.T) { tt.f(tt) })
 	}
 }
+
+func TestParameters_Lookup(t *testing.T) {
+	params := Parameters{
+		"a": map[string]interface{}{
+			"list":                   []interface{}{"x", map[string]interface{}{"b": "y"}},
+			"app.kubernetes.io/name": "render",
+		},
+	}
+	for key, want := range map[string]interface{}{
+		"a.list":                     []interface{}{"x", map[string]interface{}{"b": "y"}},
+		"a.list[1].b":                "y",
+		`a.app\.kubernetes\.io/name`: "render",
+	} {
+		got, ok, err := params.Lookup(key)
+		assert.NoError(t, err, key)
+		assert.True(t, ok, key)
+		assert.Equal(t, want, got, key)
+	}
+
+	for _, key := range []string{"missing", "a.list[2]", "a.list[]", "a.list.b"} {
+		_, ok, err := params.Lookup(key)
+		assert.NoError(t, err, key)
+		assert.False(t, ok, key)
+	}
+
+	_, _, err := params.Lookup("a..b")
+	assert.EqualError(t, err, "invalid key 'a..b': unexpected empty key")
+}
+
+func TestFromEnv(t *testing.T) {
+	environ := []string{
+		"RENDER_DB__HOST=localhost",
+		"RENDER_DB__PORT=5432",
+		"RENDER_LOG_LEVEL=debug",
+		"RENDER_APP.NAME[0]=literal",
+		"RENDER___INVALID=skipped",
+		"HOME=/home/user",
+		"OTHER_DB__HOST=other",
+	}
+
+	got, err := fromEnviron("RENDER_", environ)
+	assert.NoError(t, err)
+	assert.EqualValues(t, Parameters{
+		"db": Parameters{
+			"host": "localhost",
+			"port": "5432",
+		},
+		"log_level":   "debug",
+		"app.name[0]": "literal",
+	}, got)
+
+	t.Run("key conflict", func(t *testing.T) {
+		_, err := fromEnviron("RENDER_", []string{"RENDER_DB=x", "RENDER_DB__HOST=y"})
+		assert.EqualError(t, err, "can't use the environment variable 'RENDER_DB__HOST': "+
+			"key conflict: can't set 'db.host', 'db' is not a map, it has type: 'string'")
+	})
+
+	t.Run("precedence", func(t *testing.T) {
+		env, err := fromEnviron("RENDER_", []string{"RENDER_KEY=env", "RENDER_OTHER=env"})
+		assert.NoError(t, err)
+		got, err := All(nil, []string{"key=var"}, env)
+		assert.NoError(t, err)
+		assert.Equal(t, "var", got["key"])
+		assert.Equal(t, "env", got["other"])
+	})
+
+	t.Run("user env key", func(t *testing.T) {
+		got, err := fromEnviron("RENDER_", []string{"RENDER_KEY=env", "HOME=/home/user"})
+		assert.NoError(t, err)
+		got, err = Merge(Parameters{EnvKey: "prod"}, got)
+		assert.NoError(t, err)
+		assert.Equal(t, Parameters{EnvKey: "prod", "key": "env"}, got)
+	})
+}
+
+func TestParameters_SetEnv(t *testing.T) {
+	params := Parameters{"key": "value"}
+	err := params.setEnviron([]string{"HOME=/home/user", "EMPTY=", "=invalid"})
+	assert.NoError(t, err)
+	assert.Equal(t, Parameters{
+		"key":  "value",
+		EnvKey: Parameters{"HOME": "/home/user", "EMPTY": ""},
+	}, params)
+
+	t.Run("key already set", func(t *testing.T) {
+		params := Parameters{EnvKey: "prod"}
+		err := params.setEnviron([]string{"HOME=/home/user"})
+		assert.EqualError(t, err, "can't set the environment variables, the key 'env' is already set")
+		assert.Equal(t, Parameters{EnvKey: "prod"}, params)
+	})
+}
+
+func TestAll(t *testing.T) {
+	source := Parameters{"list": []interface{}{"a", "b"}, "labels": map[string]interface{}{"tier": "web"}}
+
+	got, err := All(nil, []string{"list[1]=x", "list[]=c", `labels.app\.kubernetes\.io/name=render`}, source)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []interface{}{"a", "x", "c"}, got["list"])
+	assert.Equal(t, map[string]interface{}{"tier": "web", "app.kubernetes.io/name": "render"}, got["labels"])
+	assert.Contains(t, got, RootKey)
+}
+
+// tempDir creates a temporary directory for the test files, it is removed when the test and its subtests finish
+func tempDir(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "render-params")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = os.RemoveAll(dir) })
+	return dir
+}
+
+// writeFile writes the file content to the slash separated name in the directory,
+// creates the parent directories and returns the path of the file
+func writeFile(t *testing.T, dir, name, content string) string {
+	p := filepath.Join(dir, filepath.FromSlash(name))
+	if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(p, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
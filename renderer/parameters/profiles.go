@@ -0,0 +1,138 @@
+package parameters
+
+import (
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// ProfilesKey is a reserved configuration file key with the named sets of values (profiles)
+	// which are merged over the other values of the file when selected, see also MergeStrategy.Profiles
+	ProfilesKey = "profiles"
+	// ExtendsKey is a reserved profile key with the name or the list of names of the extended profiles,
+	// the extended profiles are merged (in order) before the profile itself
+	ExtendsKey = "extends"
+)
+
+// profile is a named set of values, see also ProfilesKey
+type profile struct {
+	name   string
+	values map[string]interface{}
+}
+
+// selectProfiles removes the profiles from the configuration file content and returns the selected profiles
+// in the merge order, preceded by the profiles they extend, and the names of all the profiles defined in the file
+func (s MergeStrategy) selectProfiles(config map[string]interface{}) ([]profile, []string, error) {
+	value, ok := config[ProfilesKey]
+	if !ok {
+		return nil, nil, nil
+	}
+	delete(config, ProfilesKey)
+	profiles, ok := asMap(value)
+	if !ok {
+		return nil, nil, errors.Errorf("invalid '%s': expected a map of profiles, got: '%T'", ProfilesKey, value)
+	}
+
+	var defined []string
+	for name := range profiles {
+		defined = append(defined, name)
+	}
+
+	order, err := resolveProfiles(profiles, s.Profiles)
+	if err != nil {
+		return nil, nil, err
+	}
+	selected := make([]profile, len(order))
+	for i, name := range order {
+		values, _ := asMap(profiles[name])
+		selected[i] = profile{name: name, values: make(map[string]interface{}, len(values))}
+		for k, v := range values {
+			if k != ExtendsKey {
+				selected[i].values[k] = v
+			}
+		}
+	}
+	return selected, defined, nil
+}
+
+// resolveProfiles returns the names of the selected profiles defined in the profiles map,
+// preceded by the profiles they extend, each profile is listed once
+func resolveProfiles(profiles map[string]interface{}, selected []string) ([]string, error) {
+	var order []string
+	for _, name := range selected {
+		if _, ok := profiles[name]; !ok {
+			continue
+		}
+		var err error
+		order, err = resolveProfile(profiles, name, nil, order)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return order, nil
+}
+
+// resolveProfile appends the profile and the profiles it extends to the order, the stack detects the cycles
+func resolveProfile(profiles map[string]interface{}, name string, stack, order []string) ([]string, error) {
+	for i, n := range stack {
+		if n == name {
+			return nil, errors.Errorf("cycle in the profiles: %s", strings.Join(append(stack[i:], name), " -> "))
+		}
+	}
+	for _, n := range order {
+		if n == name {
+			return order, nil
+		}
+	}
+
+	profile, ok := asMap(profiles[name])
+	if !ok {
+		return nil, errors.Errorf("invalid profile '%s': expected a map, got: '%T'", name, profiles[name])
+	}
+	var extends []string
+	switch e := profile[ExtendsKey].(type) {
+	case nil:
+	case string:
+		extends = []string{e}
+	case []interface{}:
+		for _, v := range e {
+			s, ok := v.(string)
+			if !ok {
+				return nil, errors.Errorf("invalid '%s' of the profile '%s': expected a name, got: '%v'", ExtendsKey, name, v)
+			}
+			extends = append(extends, s)
+		}
+	default:
+		return nil, errors.Errorf("invalid '%s' of the profile '%s': expected a name or a list of names, got: '%T'",
+			ExtendsKey, name, e)
+	}
+
+	stack = append(stack, name)
+	for _, extended := range extends {
+		if _, ok := profiles[extended]; !ok {
+			return nil, errors.Errorf("the profile '%s' extends an unknown profile '%s'", name, extended)
+		}
+		var err error
+		order, err = resolveProfile(profiles, extended, stack, order)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return append(order, name), nil
+}
+
+// profileLines returns the lines of the profile values, keyed by the top level pointers of the values
+func profileLines(lines map[string]int, name string) map[string]int {
+	if lines == nil {
+		return nil
+	}
+	prefix := "/" + escapePointer(ProfilesKey) + "/" + escapePointer(name)
+	moved := make(map[string]int)
+	for pointer, line := range lines {
+		if strings.HasPrefix(pointer, prefix+"/") {
+			moved[strings.TrimPrefix(pointer, prefix)] = line
+		}
+	}
+	return moved
+}
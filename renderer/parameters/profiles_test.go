@@ -0,0 +1,115 @@
+package parameters
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMergeStrategy_FromFiles_Profiles(t *testing.T) {
+	dir := tempDir(t)
+
+	configPath := writeFile(t, dir, "config.yaml", `
+replicas: 1
+db:
+  host: localhost
+  port: 5432
+profiles:
+  staging:
+    replicas: 2
+    db:
+      host: db.staging
+  prod:
+    extends: staging
+    replicas: 5
+  debug:
+    debug: true
+  loop:
+    extends: [staging, loop2]
+  loop2:
+    extends: loop
+  broken:
+    extends: missing
+`)
+	otherPath := writeFile(t, dir, "other.yaml", `
+profiles:
+  prod:
+    db:
+      port: 6432
+`)
+
+	type test struct {
+		name     string
+		profiles []string
+		want     Parameters
+		wantErr  string
+	}
+
+	tests := []test{
+		{
+			name: "no profile",
+			want: Parameters{"replicas": float64(1), "db": map[string]interface{}{"host": "localhost", "port": float64(5432)}},
+		},
+		{
+			name:     "extended profile",
+			profiles: []string{"prod"},
+			want:     Parameters{"replicas": float64(5), "db": map[string]interface{}{"host": "db.staging", "port": float64(6432)}},
+		},
+		{
+			name:     "many profiles",
+			profiles: []string{"debug", "staging"},
+			want: Parameters{"replicas": float64(2), "debug": true,
+				"db": map[string]interface{}{"host": "db.staging", "port": float64(5432)}},
+		},
+		{
+			name:     "unknown profile",
+			profiles: []string{"missing"},
+			wantErr:  "unknown profile 'missing', it is not defined in any of the configuration files",
+		},
+		{
+			name:     "cycle",
+			profiles: []string{"loop"},
+			wantErr:  "can't select the profiles of the configuration file '" + configPath + "': cycle in the profiles: loop -> loop2 -> loop",
+		},
+		{
+			name:     "unknown extended profile",
+			profiles: []string{"broken"},
+			wantErr:  "can't select the profiles of the configuration file '" + configPath + "': the profile 'broken' extends an unknown profile 'missing'",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			strategy := DefaultMergeStrategy
+			strategy.Profiles = tt.profiles
+			got, err := strategy.FromFiles([]string{configPath, otherPath})
+			if len(tt.wantErr) > 0 {
+				assert.EqualError(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+
+	t.Run("invalid profiles", func(t *testing.T) {
+		invalidPath := writeFile(t, dir, "invalid.yaml", "profiles: [staging, prod]\n")
+		_, err := DefaultMergeStrategy.FromFiles([]string{invalidPath})
+		assert.EqualError(t, err, "can't select the profiles of the configuration file '"+invalidPath+
+			"': invalid 'profiles': expected a map of profiles, got: '[]interface {}'")
+	})
+
+	t.Run("origins", func(t *testing.T) {
+		origins := Origins{}
+		strategy := DefaultMergeStrategy
+		strategy.Origins = origins
+		strategy.Profiles = []string{"prod"}
+		_, err := strategy.FromFiles([]string{configPath})
+		assert.NoError(t, err)
+		assert.Equal(t, []Origin{
+			{Source: configPath, Line: 2, Value: float64(1)},
+			{Source: configPath, Line: 8, Value: float64(2)},
+			{Source: configPath, Line: 13, Value: float64(5)},
+		}, origins["/replicas"])
+	})
+}
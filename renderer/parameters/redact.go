@@ -0,0 +1,71 @@
+package parameters
+
+import (
+	"regexp"
+	"strings"
+)
+
+// RedactedValue replaces the redacted values, see also Redact
+const RedactedValue = "<redacted>"
+
+// SecretKeyPattern matches the secret-looking keys, e.g. 'password', 'db_password', 'apiKey', 'token' or 'tls_private_key'
+var SecretKeyPattern = regexp.MustCompile(`(?i)(passw(or)?d|pwd|secret|token|api[_-]?key|access[_-]?key|private[_-]?key|credential)`)
+
+// Redact returns a copy of the parameters with the values of the keys matching the pattern replaced with RedactedValue,
+// including the maps and lists, e.g. 'secrets: {...}', the parameters are not modified
+func (parameters Parameters) Redact(pattern *regexp.Regexp) Parameters {
+	return redact(parameters, pattern).(Parameters)
+}
+
+func redact(value interface{}, pattern *regexp.Regexp) interface{} {
+	if _, ok := asMap(value); ok {
+		copied, m := copyMap(value)
+		for k, v := range m {
+			if pattern.MatchString(k) {
+				m[k] = RedactedValue
+			} else {
+				m[k] = redact(v, pattern)
+			}
+		}
+		return copied
+	}
+	if list, ok := value.([]interface{}); ok {
+		copied := make([]interface{}, len(list))
+		for i, e := range list {
+			copied[i] = redact(e, pattern)
+		}
+		return copied
+	}
+	return value
+}
+
+// Redact returns a copy of the origins with the values of the keys matching the pattern replaced with RedactedValue,
+// the same way as Parameters.Redact, so the overridden values are not revealed by Explain
+func (o Origins) Redact(pattern *regexp.Regexp) Origins {
+	redacted := make(Origins, len(o))
+	for pointer, origins := range o {
+		secret := false
+		for _, token := range strings.Split(pointer, "/")[1:] {
+			if pattern.MatchString(unescapePointer(token)) {
+				secret = true
+				break
+			}
+		}
+		copied := make([]Origin, len(origins))
+		for i, origin := range origins {
+			if secret {
+				origin.Value = RedactedValue
+			} else {
+				origin.Value = redact(origin.Value, pattern)
+			}
+			copied[i] = origin
+		}
+		redacted[pointer] = copied
+	}
+	return redacted
+}
+
+// unescapePointer unescapes a JSON pointer reference token, see also escapePointer
+func unescapePointer(token string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+}
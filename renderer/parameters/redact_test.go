@@ -0,0 +1,80 @@
+package parameters
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSecretKeyPattern(t *testing.T) {
+	type test struct {
+		key  string
+		want bool
+	}
+
+	tests := []test{
+		{key: "password", want: true},
+		{key: "DB_PASSWORD", want: true},
+		{key: "passwd", want: true},
+		{key: "apiKey", want: true},
+		{key: "api-key", want: true},
+		{key: "github_token", want: true},
+		{key: "clientSecret", want: true},
+		{key: "tls_private_key", want: true},
+		{key: "aws_access_key_id", want: true},
+		{key: "credentials", want: true},
+		{key: "name", want: false},
+		{key: "host", want: false},
+		{key: "keys", want: false},
+	}
+
+	for i, tt := range tests {
+		t.Run(fmt.Sprintf("[%d] %s", i, tt.key), func(t *testing.T) {
+			assert.Equal(t, tt.want, SecretKeyPattern.MatchString(tt.key))
+		})
+	}
+}
+
+func TestParameters_Redact(t *testing.T) {
+	params := Parameters{
+		"name": "render",
+		"db": Parameters{
+			"host":     "localhost",
+			"password": "hunter2",
+		},
+		"users": []interface{}{
+			map[string]interface{}{"name": "admin", "token": "abc"},
+		},
+		"secrets": map[string]interface{}{"a": "b"},
+	}
+
+	redacted := params.Redact(SecretKeyPattern)
+	assert.Equal(t, Parameters{
+		"name": "render",
+		"db": Parameters{
+			"host":     "localhost",
+			"password": RedactedValue,
+		},
+		"users": []interface{}{
+			map[string]interface{}{"name": "admin", "token": RedactedValue},
+		},
+		"secrets": RedactedValue,
+	}, redacted)
+	assert.Equal(t, "hunter2", params["db"].(Parameters)["password"], "unexpected modification of the parameters")
+}
+
+func TestOrigins_Redact(t *testing.T) {
+	origins := Origins{
+		"/db":          {{Source: "base.yaml", Value: Parameters{"password": "a", "host": "b"}}},
+		"/db/password": {{Source: "base.yaml", Line: 2, Value: "a"}, {Source: "--set[0]", Value: "c"}},
+		"/db/host":     {{Source: "base.yaml", Line: 3, Value: "b"}},
+	}
+
+	assert.Equal(t, Origins{
+		"/db":          {{Source: "base.yaml", Value: Parameters{"password": RedactedValue, "host": "b"}}},
+		"/db/password": {{Source: "base.yaml", Line: 2, Value: RedactedValue}, {Source: "--set[0]", Value: RedactedValue}},
+		"/db/host":     {{Source: "base.yaml", Line: 3, Value: "b"}},
+	}, origins.Redact(SecretKeyPattern))
+	assert.Equal(t, "a", origins["/db/password"][0].Value, "unexpected modification of the origins")
+}
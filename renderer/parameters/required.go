@@ -0,0 +1,107 @@
+package parameters
+
+import (
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strings"
+
+	"github.com/ghodss/yaml"
+	"github.com/pkg/errors"
+)
+
+// Requirement is a required parameter, see also Validate
+type Requirement struct {
+	// Key is the key of the parameter, see also SetVars for the key syntax
+	Key         string
+	Description string
+}
+
+func (r Requirement) String() string {
+	if len(r.Description) == 0 {
+		return r.Key
+	}
+	return fmt.Sprintf("%s: %s", r.Key, r.Description)
+}
+
+// MissingError lists all the missing required parameters
+type MissingError struct {
+	Missing []Requirement
+}
+
+func (e *MissingError) Error() string {
+	messages := make([]string, len(e.Missing))
+	for i, requirement := range e.Missing {
+		messages[i] = requirement.String()
+	}
+	return fmt.Sprintf("missing %d required parameter(s):\n\t%s", len(e.Missing), strings.Join(messages, "\n\t"))
+}
+
+// Validate checks if all the required parameters are set, a nil value is treated as missing,
+// all the missing parameters are reported at once with a MissingError
+func (parameters Parameters) Validate(requirements ...Requirement) error {
+	var missing []Requirement
+	for _, requirement := range requirements {
+		segments, err := parseKey(requirement.Key)
+		if err != nil {
+			return errors.Wrap(err, "invalid required parameter")
+		}
+		value, ok := lookup(parameters, segments)
+		if !ok || value == nil {
+			missing = append(missing, requirement)
+		}
+	}
+	if len(missing) > 0 {
+		return &MissingError{Missing: missing}
+	}
+	return nil
+}
+
+// RequirementsFromFiles reads the required parameters from one or more files, each file is a map of the keys
+// to their descriptions (e.g. 'db.host: the database host'), or a list of the keys in YAML or JSON,
+// the format is detected the same way as for the configuration files, see also FormatOf
+func RequirementsFromFiles(paths []string) ([]Requirement, error) {
+	var requirements []Requirement
+	for _, path := range paths {
+		format, path := FormatOf(path)
+		b, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, errors.Wrapf(err, "can't read the required parameters file '%s'", path)
+		}
+
+		var document interface{}
+		if format == YAMLFormat || format == JSONFormat {
+			err = yaml.Unmarshal(b, &document)
+		} else {
+			document, err = unmarshal(format, b)
+		}
+		if err != nil {
+			return nil, errors.Wrapf(err, "can't parse the required parameters file '%s'", path)
+		}
+
+		switch d := document.(type) {
+		case nil:
+		case []interface{}:
+			for _, key := range d {
+				requirements = append(requirements, Requirement{Key: fmt.Sprintf("%v", key)})
+			}
+		case map[string]interface{}:
+			keys := make([]string, 0, len(d))
+			for key := range d {
+				keys = append(keys, key)
+			}
+			sort.Strings(keys)
+			for _, key := range keys {
+				requirement := Requirement{Key: key}
+				if d[key] != nil {
+					requirement.Description = fmt.Sprintf("%v", d[key])
+				}
+				requirements = append(requirements, requirement)
+			}
+		default:
+			return nil, errors.Errorf("invalid required parameters file '%s': expected a map or a list of keys, got: '%T'",
+				path, document)
+		}
+	}
+	return requirements, nil
+}
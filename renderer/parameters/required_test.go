@@ -0,0 +1,62 @@
+package parameters
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParameters_Validate(t *testing.T) {
+	params := Parameters{
+		"name":  "render",
+		"empty": "",
+		"null":  nil,
+		"db":    Parameters{"host": "localhost"},
+		"ports": []interface{}{80},
+	}
+
+	assert.NoError(t, params.Validate())
+	assert.NoError(t, params.Validate(
+		Requirement{Key: "name"},
+		Requirement{Key: "empty"},
+		Requirement{Key: "db.host"},
+		Requirement{Key: "ports[0]"},
+	))
+
+	err := params.Validate(
+		Requirement{Key: "name"},
+		Requirement{Key: "null", Description: "a nil value"},
+		Requirement{Key: "db.port", Description: "the database port"},
+		Requirement{Key: "ports[1]"},
+	)
+	assert.Equal(t, &MissingError{Missing: []Requirement{
+		{Key: "null", Description: "a nil value"},
+		{Key: "db.port", Description: "the database port"},
+		{Key: "ports[1]"},
+	}}, err)
+	assert.EqualError(t, err, "missing 3 required parameter(s):\n\tnull: a nil value\n\tdb.port: the database port\n\tports[1]")
+
+	err = params.Validate(Requirement{Key: "a..b"})
+	assert.Error(t, err)
+}
+
+func TestRequirementsFromFiles(t *testing.T) {
+	dir := tempDir(t)
+
+	mapPath := writeFile(t, dir, "required.yaml", "replicas:\ndb.host: the database host\n")
+	listPath := writeFile(t, dir, "required.json", `["name", "labels.app\\.kubernetes\\.io/name"]`)
+	envPath := writeFile(t, dir, "required.env", "TOKEN=the API token\n")
+
+	requirements, err := RequirementsFromFiles([]string{mapPath, listPath, envPath})
+	assert.NoError(t, err)
+	assert.Equal(t, []Requirement{
+		{Key: "db.host", Description: "the database host"},
+		{Key: "replicas"},
+		{Key: "name"},
+		{Key: `labels.app\.kubernetes\.io/name`},
+		{Key: "TOKEN", Description: "the API token"},
+	}, requirements)
+
+	_, err = RequirementsFromFiles([]string{writeFile(t, dir, "invalid.yaml", "just a string")})
+	assert.Error(t, err)
+}
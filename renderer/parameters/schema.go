@@ -0,0 +1,144 @@
+package parameters
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strings"
+
+	"github.com/ghodss/yaml"
+	"github.com/pkg/errors"
+	"github.com/santhosh-tekuri/jsonschema/v5"
+)
+
+// SchemaViolation is a single parameter value not matching the schema, see also ValidateAgainstSchema
+type SchemaViolation struct {
+	// Pointer is the JSON pointer of the value, e.g. '/db/port', empty for the root
+	Pointer string
+	// Origin is the source of the value, e.g. 'prod.yaml:3' or '--set[0]', see also Origins
+	Origin  string
+	Message string
+}
+
+func (v SchemaViolation) String() string {
+	pointer := v.Pointer
+	if len(pointer) == 0 {
+		pointer = "/"
+	}
+	if len(v.Origin) == 0 {
+		return fmt.Sprintf("'%s': %s", pointer, v.Message)
+	}
+	return fmt.Sprintf("'%s' (from '%s'): %s", pointer, v.Origin, v.Message)
+}
+
+// SchemaError aggregates all the schema violations of the parameters
+type SchemaError struct {
+	Schema     string
+	Violations []SchemaViolation
+}
+
+func (e *SchemaError) Error() string {
+	messages := make([]string, len(e.Violations))
+	for i, violation := range e.Violations {
+		messages[i] = violation.String()
+	}
+	return fmt.Sprintf("the parameters don't match the schema '%s', %d violation(s):\n\t%s",
+		e.Schema, len(e.Violations), strings.Join(messages, "\n\t"))
+}
+
+// ValidateAgainstSchema validates the parameters against a JSON Schema file, the draft is selected
+// with the '$schema' keyword (e.g. draft 7), draft 2020-12 is used if it is missing, the schema file
+// can be also written in YAML, see also FormatOf; all the violations are reported with a SchemaError,
+// including the origins of the values if the origins are not nil; the reserved keys RootKey and EnvKey
+// are not validated, so e.g. 'additionalProperties: false' does not have to declare them
+func ValidateAgainstSchema(parameters Parameters, schemaPath string, origins Origins) error {
+	schema, err := compileSchema(schemaPath)
+	if err != nil {
+		return errors.Wrapf(err, "can't load the schema '%s'", schemaPath)
+	}
+
+	validated := make(Parameters, len(parameters))
+	for key, value := range parameters {
+		if key != RootKey && key != EnvKey {
+			validated[key] = value
+		}
+	}
+
+	// the schema validator expects the values as decoded from JSON, e.g. json.Number instead of int
+	b, err := json.Marshal(validated)
+	if err != nil {
+		return errors.Wrap(err, "can't convert the parameters to JSON")
+	}
+	decoder := json.NewDecoder(bytes.NewReader(b))
+	decoder.UseNumber()
+	var instance interface{}
+	err = decoder.Decode(&instance)
+	if err != nil {
+		return errors.Wrap(err, "can't convert the parameters to JSON")
+	}
+
+	err = schema.Validate(instance)
+	validationError, ok := err.(*jsonschema.ValidationError)
+	if !ok {
+		return errors.WithStack(err)
+	}
+	schemaError := &SchemaError{Schema: schemaPath}
+	seen := make(map[SchemaViolation]bool)
+	for _, cause := range leafCauses(validationError) {
+		violation := SchemaViolation{
+			Pointer: cause.InstanceLocation,
+			Origin:  origins.Of(cause.InstanceLocation).String(),
+			Message: cause.Message,
+		}
+		if !seen[violation] {
+			seen[violation] = true
+			schemaError.Violations = append(schemaError.Violations, violation)
+		}
+	}
+	sort.SliceStable(schemaError.Violations, func(i, j int) bool {
+		return schemaError.Violations[i].Pointer < schemaError.Violations[j].Pointer
+	})
+	return schemaError
+}
+
+// compileSchema reads and compiles the JSON or YAML schema file
+func compileSchema(schemaPath string) (*jsonschema.Schema, error) {
+	format, schemaPath := FormatOf(schemaPath)
+	b, err := ioutil.ReadFile(schemaPath)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	switch format {
+	case JSONFormat:
+	case YAMLFormat:
+		b, err = yaml.YAMLToJSON(b)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+	default:
+		return nil, errors.Errorf("unexpected schema format: '%s', format must be in: '%s'",
+			format, []Format{JSONFormat, YAMLFormat})
+	}
+
+	compiler := jsonschema.NewCompiler()
+	err = compiler.AddResource(schemaPath, bytes.NewReader(b))
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	schema, err := compiler.Compile(schemaPath)
+	return schema, errors.WithStack(err)
+}
+
+// leafCauses returns the most specific validation errors
+func leafCauses(err *jsonschema.ValidationError) []*jsonschema.ValidationError {
+	if len(err.Causes) == 0 {
+		return []*jsonschema.ValidationError{err}
+	}
+	var leaves []*jsonschema.ValidationError
+	for _, cause := range err.Causes {
+		leaves = append(leaves, leafCauses(cause)...)
+	}
+	return leaves
+}
@@ -0,0 +1,89 @@
+package parameters
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateAgainstSchema(t *testing.T) {
+	dir := tempDir(t)
+
+	jsonSchemaPath := writeFile(t, dir, "values.schema.json", `{
+  "$schema": "http://json-schema.org/draft-07/schema#",
+  "type": "object",
+  "required": ["db", "name"],
+  "properties": {
+    "db": {
+      "type": "object",
+      "required": ["host"],
+      "properties": {"port": {"type": "integer", "maximum": 65535}}
+    },
+    "replicas": {"type": "integer", "minimum": 1}
+  }
+}`)
+	yamlSchemaPath := writeFile(t, dir, "values.schema.yaml", `
+type: object
+required: [name]
+properties:
+  name:
+    type: string
+`)
+	strictSchemaPath := writeFile(t, dir, "strict.schema.yaml", `
+type: object
+additionalProperties: false
+properties:
+  name:
+    type: string
+`)
+	configPath := writeFile(t, dir, "config.yaml", "db:\n  port: 70000\nreplicas: 2\n")
+
+	origins := Origins{}
+	strategy := DefaultMergeStrategy
+	strategy.Origins = origins
+	params, err := strategy.All([]string{configPath}, []string{"replicas=two"})
+	assert.NoError(t, err)
+
+	err = ValidateAgainstSchema(params, jsonSchemaPath, origins)
+	assert.Equal(t, &SchemaError{
+		Schema: jsonSchemaPath,
+		Violations: []SchemaViolation{
+			{Pointer: "", Message: "missing properties: 'name'"},
+			{Pointer: "/db", Origin: configPath + ":1", Message: "missing properties: 'host'"},
+			{Pointer: "/db/port", Origin: configPath + ":2", Message: "must be <= 65535 but found 70000"},
+			{Pointer: "/replicas", Origin: "--set[0]", Message: "expected integer, but got string"},
+		},
+	}, err)
+
+	t.Run("without origins", func(t *testing.T) {
+		err := ValidateAgainstSchema(Parameters{"name": 1}, yamlSchemaPath, nil)
+		assert.EqualError(t, err, "the parameters don't match the schema '"+yamlSchemaPath+
+			"', 1 violation(s):\n\t'/name': expected string, but got number")
+	})
+
+	t.Run("valid", func(t *testing.T) {
+		err := ValidateAgainstSchema(Parameters{"name": "render", "nested": Parameters{"a": int64(1)}}, yamlSchemaPath, nil)
+		assert.NoError(t, err)
+	})
+
+	t.Run("reserved keys", func(t *testing.T) {
+		params, err := All(nil, []string{"name=render"}, Parameters{EnvKey: Parameters{"HOME": "/home/user"}})
+		assert.NoError(t, err)
+		assert.Contains(t, params, RootKey)
+
+		err = ValidateAgainstSchema(params, strictSchemaPath, nil)
+		assert.NoError(t, err)
+
+		err = ValidateAgainstSchema(Parameters{"name": "render", "other": 1}, strictSchemaPath, nil)
+		assert.EqualError(t, err, "the parameters don't match the schema '"+strictSchemaPath+
+			"', 1 violation(s):\n\t'/': additionalProperties 'other' not allowed")
+	})
+
+	t.Run("missing schema", func(t *testing.T) {
+		err := ValidateAgainstSchema(Parameters{}, filepath.Join(dir, "missing.json"), nil)
+		assert.Error(t, err)
+		_, ok := err.(*SchemaError)
+		assert.False(t, ok)
+	})
+}
@@ -0,0 +1,209 @@
+package renderer
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// ManifestFileName is the name of the file in the output directory with the outputs
+// of the last directory mode run, used to prune the stale outputs, see also WithPrune
+const ManifestFileName = ".render-manifest"
+
+const manifestHeader = "# the outputs of the last render run, used by --prune, do not edit"
+
+// manifestLine matches a manifest line with the SHA-256 of the content and the path, the same way as sha256sum does
+var manifestLine = regexp.MustCompile(`^([0-9a-f]{64})  (.+)$`)
+
+// WithPrune mutates Renderer configuration by enabling the pruning in the directory mode,
+// the outputs are recorded with the hashes of their content in a manifest file in the output directory
+// and the outputs recorded by the previous run, that no longer have a source, are deleted,
+// other files and the recorded outputs changed since they were written are never touched
+func WithPrune() Option {
+	return func(c *extraConfig) {
+		c.prune = true
+	}
+}
+
+// prune deletes the outputs recorded in the previous manifest that were not produced by the given tasks
+// and records the new outputs, in the check mode the stale outputs are returned instead
+func (r *renderer) prune(outputDir string, previous []manifestEntry, tasks []dirTask) ([]string, error) {
+	manifestPath := filepath.Join(outputDir, ManifestFileName)
+	var err error
+	outputs := make(map[string]string, len(tasks)) // the hashes by the slash separated relative paths
+	for _, task := range tasks {
+		if task.inputPath == task.outputPath {
+			continue // rendered in place, the file is a source rather than an output
+		}
+		rel, err := filepath.Rel(outputDir, task.outputPath)
+		if err != nil {
+			return nil, errors.Wrapf(err, "can't get a relative path for: '%s'", task.outputPath)
+		}
+		outputs[filepath.ToSlash(rel)] = ""
+	}
+
+	var stale []string
+	for _, entry := range previous {
+		if _, ok := outputs[entry.path]; ok {
+			continue
+		}
+		stalePath := filepath.Join(outputDir, filepath.FromSlash(entry.path))
+		info, err := os.Lstat(stalePath)
+		if os.IsNotExist(err) {
+			continue
+		} else if err != nil {
+			return nil, errors.Wrapf(err, "can't get file information for '%s'", stalePath)
+		}
+		if len(entry.hash) == 0 || !info.Mode().IsRegular() {
+			logrus.Warnf("Not pruning '%s', its content is not recorded in the manifest", stalePath)
+			continue
+		}
+		hash, err := fileHash(stalePath)
+		if err != nil {
+			return nil, err
+		}
+		if hash != entry.hash {
+			logrus.Warnf("Not pruning '%s', it was changed since it was rendered", stalePath)
+			continue
+		}
+		stale = append(stale, stalePath)
+	}
+
+	if r.extra.check {
+		for _, stalePath := range stale {
+			logrus.Debugf("Stale output detected: '%s'", stalePath)
+			if r.extra.diff != nil {
+				_, err := fmt.Fprintf(r.extra.diff, "Stale file %s would be pruned\n", stalePath)
+				if err != nil {
+					return nil, errors.WithStack(err)
+				}
+			}
+		}
+		return stale, nil
+	}
+
+	for _, stalePath := range stale {
+		logrus.Infof("Pruning '%s'", stalePath)
+		err := os.Remove(stalePath)
+		if err != nil {
+			return nil, errors.Wrapf(err, "can't prune the file: '%s'", stalePath)
+		}
+		removeEmptyDirs(outputDir, filepath.Dir(stalePath))
+	}
+
+	for rel := range outputs {
+		outputs[rel], err = fileHash(filepath.Join(outputDir, filepath.FromSlash(rel)))
+		if err != nil {
+			return nil, err
+		}
+	}
+	return nil, writeManifest(manifestPath, outputs)
+}
+
+// manifestEntry is an output recorded in the manifest
+type manifestEntry struct {
+	path string // slash separated, relative to the output directory
+	hash string // the SHA-256 of the content, empty if unknown
+}
+
+// readManifest returns the outputs recorded in the manifest, skips the unsafe paths
+func readManifest(manifestPath string) ([]manifestEntry, error) {
+	f, err := os.Open(manifestPath)
+	if os.IsNotExist(err) {
+		logrus.Debugf("No manifest file: '%s'", manifestPath)
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "can't open the manifest file: '%s'", manifestPath)
+	}
+	defer func() { _ = f.Close() }()
+
+	var entries []manifestEntry
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if len(line) == 0 || strings.HasPrefix(line, "#") {
+			continue
+		}
+		var hash string
+		if match := manifestLine.FindStringSubmatch(line); match != nil {
+			hash, line = match[1], match[2]
+		}
+		clean := path.Clean(line)
+		if path.IsAbs(clean) || clean == "." || clean == ".." || strings.HasPrefix(clean, "../") {
+			logrus.Warnf("Ignoring a path outside of the output directory in the manifest '%s': '%s'", manifestPath, line)
+			continue
+		}
+		entries = append(entries, manifestEntry{path: clean, hash: hash})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, errors.Wrapf(err, "can't read the manifest file: '%s'", manifestPath)
+	}
+	return entries, nil
+}
+
+// writeManifest records the outputs with the hashes of their content, keyed by the slash separated relative paths
+func writeManifest(manifestPath string, outputs map[string]string) error {
+	paths := make([]string, 0, len(outputs))
+	for rel := range outputs {
+		paths = append(paths, rel)
+	}
+	sort.Strings(paths)
+
+	content := manifestHeader + "\n"
+	for _, rel := range paths {
+		content += outputs[rel] + "  " + rel + "\n"
+	}
+	logrus.Debugf("Writing the manifest file: '%s'", manifestPath)
+	err := os.MkdirAll(filepath.Dir(manifestPath), os.ModePerm)
+	if err != nil {
+		return errors.Wrapf(err, "can't create the output directory: '%s'", filepath.Dir(manifestPath))
+	}
+	err = ioutil.WriteFile(manifestPath, []byte(content), 0644)
+	if err != nil {
+		return errors.Wrapf(err, "can't write the manifest file: '%s'", manifestPath)
+	}
+	return nil
+}
+
+// fileHash returns the hex encoded SHA-256 of the file content
+func fileHash(filePath string) (string, error) {
+	f, err := os.Open(filePath)
+	if err != nil {
+		return "", errors.Wrapf(err, "can't open the file: '%s'", filePath)
+	}
+	defer func() { _ = f.Close() }()
+	hash := sha256.New()
+	_, err = io.Copy(hash, f)
+	if err != nil {
+		return "", errors.Wrapf(err, "can't read the file: '%s'", filePath)
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// removeEmptyDirs removes the empty directories from the given one up to the root (exclusive)
+func removeEmptyDirs(root, dir string) {
+	for {
+		rel, err := filepath.Rel(root, dir)
+		if err != nil || rel == "." || strings.HasPrefix(rel, "..") {
+			return
+		}
+		if os.Remove(dir) != nil {
+			return // not empty
+		}
+		logrus.Infof("Empty directory was removed: '%s'", dir)
+		dir = filepath.Dir(dir)
+	}
+}
@@ -0,0 +1,198 @@
+package renderer
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRenderer_DirRender_Prune(t *testing.T) {
+	params := map[string]interface{}{"value": "some"}
+
+	Run(t, Test{
+		name: "dir render with prune",
+		f: func(tt Test) {
+			inputDir, outputDir := tempDirs(t)
+			defer cleanup(inputDir, outputDir)
+			writeTree(t, inputDir, map[string]string{
+				"a.tmpl":     "{{ .value }}",
+				"sub/b.tmpl": "{{ .value }}",
+				"c.txt":      "copied",
+			})
+			writeTree(t, outputDir, map[string]string{"foreign": "not ours"})
+
+			err := NewWithOptions([]Option{WithPrune()}, WithParameters(params)).DirRender(inputDir, outputDir)
+			assert.NoError(t, err, tt.name)
+			assert.Equal(t, map[string]string{
+				"a":              "some",
+				"sub/b":          "some",
+				"c.txt":          "copied",
+				"foreign":        "not ours",
+				ManifestFileName: manifest(map[string]string{"a": "some", "c.txt": "copied", "sub/b": "some"}),
+			}, readTree(t, outputDir))
+
+			assert.NoError(t, os.RemoveAll(filepath.Join(inputDir, "sub")))
+			assert.NoError(t, os.Remove(filepath.Join(inputDir, "c.txt")))
+
+			err = NewWithOptions([]Option{WithPrune()}, WithParameters(params)).DirRender(inputDir, outputDir)
+			assert.NoError(t, err, tt.name)
+			assert.Equal(t, map[string]string{
+				"a":              "some",
+				"foreign":        "not ours",
+				ManifestFileName: manifest(map[string]string{"a": "some"}),
+			}, readTree(t, outputDir))
+			_, err = os.Stat(filepath.Join(outputDir, "sub"))
+			assert.True(t, os.IsNotExist(err), "the empty directory should be removed")
+			assert.Equal(t, 0, CountProblems(tt.logHook))
+		},
+	})
+
+	for _, mode := range []DirMode{CopyNonTemplatesMode, RenderAllMode} {
+		Run(t, Test{
+			name: fmt.Sprintf("dir render in place with prune in the %s mode", mode),
+			f: func(tt Test) {
+				dir, outputDir := tempDirs(t)
+				defer cleanup(dir, outputDir)
+				writeTree(t, dir, map[string]string{
+					"a.yaml.tmpl": "{{ .value }}",
+					"c.txt":       "source",
+				})
+				options := []Option{WithPrune(), WithDirMode(mode)}
+
+				err := NewWithOptions(options, WithParameters(params)).DirRender(dir, dir)
+				assert.NoError(t, err, tt.name)
+				assert.Equal(t, map[string]string{
+					"a.yaml.tmpl":    "{{ .value }}",
+					"a.yaml":         "some",
+					"c.txt":          "source",
+					ManifestFileName: manifest(map[string]string{"a.yaml": "some"}),
+				}, readTree(t, dir))
+
+				assert.NoError(t, os.Remove(filepath.Join(dir, "a.yaml.tmpl")))
+
+				err = NewWithOptions(options, WithParameters(params)).DirRender(dir, dir)
+				assert.NoError(t, err, tt.name)
+				assert.Equal(t, map[string]string{
+					"c.txt":          "source",
+					ManifestFileName: manifest(map[string]string{}),
+				}, readTree(t, dir))
+				assert.Equal(t, 0, CountProblems(tt.logHook))
+			},
+		})
+	}
+
+	Run(t, Test{
+		name: "dir render with prune refuses paths outside of the output directory",
+		f: func(tt Test) {
+			inputDir, outputDir := tempDirs(t)
+			defer cleanup(inputDir, outputDir)
+			writeTree(t, inputDir, map[string]string{"a.tmpl": "{{ .value }}"})
+			outside, err := ioutil.TempFile(filepath.Dir(outputDir), "render-outside")
+			if err != nil {
+				t.Fatal(err)
+			}
+			_ = outside.Close()
+			defer cleanup(outside.Name())
+			writeTree(t, outputDir, map[string]string{
+				ManifestFileName: "../" + filepath.Base(outside.Name()) + "\n" + outside.Name() + "\n",
+			})
+
+			err = NewWithOptions([]Option{WithPrune()}, WithParameters(params)).DirRender(inputDir, outputDir)
+			assert.NoError(t, err, tt.name)
+			_, err = os.Stat(outside.Name())
+			assert.NoError(t, err, "the file outside of the output directory should not be touched")
+			assert.Equal(t, 2, CountProblems(tt.logHook))
+		},
+	})
+
+	Run(t, Test{
+		name: "dir render with prune skips pruning on errors",
+		f: func(tt Test) {
+			inputDir, outputDir := tempDirs(t)
+			defer cleanup(inputDir, outputDir)
+			writeTree(t, inputDir, map[string]string{"a.tmpl": "{{ broken"})
+			writeTree(t, outputDir, map[string]string{
+				"stale":          "some",
+				ManifestFileName: "stale\n",
+			})
+
+			err := NewWithOptions([]Option{WithPrune()}, WithParameters(params)).DirRender(inputDir, outputDir)
+			assert.Error(t, err, tt.name)
+			assert.Equal(t, map[string]string{
+				"stale":          "some",
+				ManifestFileName: "stale\n",
+			}, readTree(t, outputDir))
+		},
+	})
+
+	Run(t, Test{
+		name: "dir check with prune",
+		f: func(tt Test) {
+			inputDir, outputDir := tempDirs(t)
+			defer cleanup(inputDir, outputDir)
+			writeTree(t, inputDir, map[string]string{"a.tmpl": "{{ .value }}"})
+			writeTree(t, outputDir, map[string]string{
+				"a":              "some",
+				"stale":          "some",
+				ManifestFileName: manifest(map[string]string{"a": "some", "stale": "some"}),
+			})
+			stale := filepath.Join(outputDir, "stale")
+
+			var diff bytes.Buffer
+			err := NewWithOptions([]Option{WithPrune(), WithCheck(&diff)}, WithParameters(params)).DirRender(inputDir, outputDir)
+			assert.Equal(t, &DriftError{Paths: []string{stale}}, err, tt.name)
+			assert.Equal(t, "Stale file "+stale+" would be pruned\n", diff.String())
+			assert.Equal(t, map[string]string{
+				"a":              "some",
+				"stale":          "some",
+				ManifestFileName: manifest(map[string]string{"a": "some", "stale": "some"}),
+			}, readTree(t, outputDir))
+		},
+	})
+
+	Run(t, Test{
+		name: "dir render with prune keeps the changed outputs",
+		f: func(tt Test) {
+			inputDir, outputDir := tempDirs(t)
+			defer cleanup(inputDir, outputDir)
+			writeTree(t, inputDir, map[string]string{"a.tmpl": "{{ .value }}"})
+			writeTree(t, outputDir, map[string]string{
+				"stale":          "some",
+				"changed":        "hand-written",
+				"unknown":        "some",
+				ManifestFileName: manifest(map[string]string{"stale": "some", "changed": "some"}) + "unknown\n",
+			})
+
+			err := NewWithOptions([]Option{WithPrune()}, WithParameters(params)).DirRender(inputDir, outputDir)
+			assert.NoError(t, err, tt.name)
+			assert.Equal(t, map[string]string{
+				"a":              "some",
+				"changed":        "hand-written",
+				"unknown":        "some",
+				ManifestFileName: manifest(map[string]string{"a": "some"}),
+			}, readTree(t, outputDir))
+			assert.Equal(t, 2, CountProblems(tt.logHook))
+		},
+	})
+}
+
+// manifest returns the manifest file content with the outputs and their content
+func manifest(outputs map[string]string) string {
+	paths := make([]string, 0, len(outputs))
+	for p := range outputs {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+	content := manifestHeader + "\n"
+	for _, p := range paths {
+		content += fmt.Sprintf("%x  %s\n", sha256.Sum256([]byte(outputs[p])), p)
+	}
+	return content
+}
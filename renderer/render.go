@@ -2,17 +2,14 @@ package renderer
 
 import (
 	"fmt"
+	"io"
 	"os"
-	"path"
-	"path/filepath"
-	"strings"
 	"text/template"
 
 	"github.com/VirtusLab/render/renderer/parameters"
 
 	"github.com/Masterminds/sprig/v3"
 	crypto "github.com/VirtusLab/crypt/crypto/render"
-	"github.com/VirtusLab/go-extended/pkg/files"
 	base "github.com/VirtusLab/go-extended/pkg/renderer"
 	"github.com/VirtusLab/go-extended/pkg/renderer/config"
 	"github.com/imdario/mergo"
@@ -24,31 +21,77 @@ import (
 type Renderer interface {
 	base.Renderer
 	Clone(configurators ...func(*config.Config)) Renderer
+	Configure(options ...Option)
 	FileRender(inputPath, outputPath string) error
 	DirRender(inputDir, outputDir string) error
+	ForeachRender(inputPath, listKey, outputPathTemplate string) error
 	NestedRender(args ...interface{}) (string, error)
 	ReadFile(file string) (string, error)
 }
 
 type renderer struct {
 	base.Renderer
+	extra extraConfig
 }
 
+// extraConfig holds the renderer configuration not covered by config.Config,
+// e.g. the directory mode configuration, see also DirRender
+type extraConfig struct {
+	ignorePatterns     []string
+	mode               DirMode
+	templateExtensions []string
+	jobs               int
+	check              bool
+	diff               io.Writer
+	prune              bool
+	atomic             bool
+	frontMatter        bool
+	extensionDelims    []extensionDelims
+	onReadFile         func(absPath string)
+}
+
+// Option mutates the renderer configuration not covered by config.Config,
+// e.g. the directory mode configuration, see also NewWithOptions and Configure
+type Option func(*extraConfig)
+
 // New creates a new renderer with the specified parameters and zero or more options
 func New(configurators ...func(*config.Config)) Renderer {
+	return NewWithOptions(nil, configurators...)
+}
+
+// NewWithOptions creates a new renderer with the options, e.g. WithIgnorePatterns, and zero or more configurators
+func NewWithOptions(options []Option, configurators ...func(*config.Config)) Renderer {
 	r := &renderer{
-		Renderer: base.New(configurators...),
+		Renderer: base.New(),
 	}
-	r.Reconfigure(
-		WithMoreFunctions(template.FuncMap{
-			"render":    r.NestedRender,
-			"readFile":  r.ReadFile,
-			"writeFile": r.WriteFile,
-		}),
-	)
+	r.Reconfigure(configurators...)
+	r.Configure(options...)
+	r.bindFunctions()
 	return r
 }
 
+// bindFunctions adds the template functions bound to this renderer instance,
+// the functions map is copied, so the functions of other instances, e.g. clones, are not affected
+func (r *renderer) bindFunctions() {
+	r.Renderer.Reconfigure(func(c *config.Config) {
+		functions := make(template.FuncMap, len(c.ExtraFunctions)+3)
+		for name, function := range c.ExtraFunctions {
+			functions[name] = function
+		}
+		functions["render"] = r.NestedRender
+		functions["readFile"] = r.ReadFile
+		functions["writeFile"] = r.WriteFile
+		c.ExtraFunctions = functions
+	})
+}
+
+// Configure mutates the renderer configuration not covered by config.Config with the options, e.g. WithCheck
+func (r *renderer) Configure(options ...Option) {
+	for _, option := range options {
+		option(&r.extra)
+	}
+}
+
 // WithParameters mutates Renderer configuration by replacing all template parameters
 func WithParameters(parameters map[string]interface{}) func(*config.Config) {
 	return base.WithParameters(parameters)
@@ -123,52 +166,7 @@ func MergeFunctions(dst *template.FuncMap, src template.FuncMap) error {
 	return nil
 }
 
-// TODO parametrize
-var defaultTemplateExtensions = []string{".tpl", ".tmpl"}
-
-// DirRender is used to render files by directory, see also FileRender
-// TODO break up to multiple small functions
-func (r *renderer) DirRender(inputDir, outputDir string) error {
-	logrus.Infof("Directory mode selected: '%s' -> '%s'", inputDir, outputDir)
-
-	fileEntries, err := dirTree(inputDir)
-	if err != nil {
-		return errors.Wrapf(err, "can't scan the directory tree: '%s'", inputDir)
-	}
-
-	for _, file := range fileEntries {
-		logrus.Debugf("Processing '%s'", path.Join(file.path, file.name))
-
-		target := trimExtension(file, defaultTemplateExtensions)
-
-		rel, err := filepath.Rel(inputDir, file.path)
-		if err != nil {
-			return errors.Wrapf(err, "can't get a relative path for: '%s'", file.path)
-		}
-
-		target.path = path.Join(outputDir, rel)
-
-		_, err = os.Stat(target.path)
-		if os.IsNotExist(err) {
-			err := os.MkdirAll(target.path, os.ModePerm)
-			if err != nil {
-				return errors.Wrapf(err, "can't create the target directory: '%s'", target.path)
-			}
-			logrus.Infof("Target directory was created: '%s'", target.path)
-		} else if err != nil {
-			return errors.Wrapf(err, "can't get file information for '%s'", target.path)
-		}
-
-		err = r.FileRender(path.Join(file.path, file.name), path.Join(target.path, target.name))
-		if err != nil {
-			return errors.Wrap(err, "can't render a file")
-		}
-	}
-
-	return nil
-}
-
-// FileRender is used to render files by path, see also DirRender
+// FileRender is used to render files by path, see also DirRender, ForeachRender, WithCheck and WithFrontMatter
 func (r *renderer) FileRender(inputPath, outputPath string) error {
 	inputName := inputPath
 	outputName := outputPath
@@ -180,41 +178,67 @@ func (r *renderer) FileRender(inputPath, outputPath string) error {
 	}
 	logrus.Infof("Rendering '%s' -> '%s'\n", inputName, outputName)
 
-	input, err := files.ReadInput(inputPath)
+	t, err := r.readTemplate(inputPath)
 	if err != nil {
-		logrus.Debugf("Can't open the template: %v", err)
 		return err
 	}
-
-	var templateName string
-	if inputPath == "" {
-		templateName = "stdin"
-	} else {
-		templateName = inputPath
+	if t.front != nil && t.front.skip {
+		logrus.Infof("Skipping '%s', as set in the front matter", inputName)
+		return nil
 	}
-
-	inputString := string(input)
-	logrus.Debugf("%s: \n%s", inputName, inputString)
-	result, err := r.NamedRender(templateName, inputString)
+	result, err := r.renderTemplate(t)
 	if err != nil {
 		return err
 	}
 	logrus.Debugf("%s: \n%s", outputName, result)
 
-	err = files.WriteOutput(outputPath, []byte(result), 0644)
-	if err != nil {
-		logrus.Debugf("Can't save the rendered file: %v", err)
-		return err
+	return r.output(outputPath, result, t.mode())
+}
+
+// output writes the rendered result to the output file with the file mode (the default one if zero),
+// stdout is used if the path is empty, in the check mode the result is compared with the existing output file instead
+func (r *renderer) output(outputPath, result string, mode os.FileMode) error {
+	if r.extra.check {
+		if outputPath == "" {
+			return errors.New("the check mode requires an output file")
+		}
+		drift, err := checkOutput(outputPath, []byte(result), r.extra.diff)
+		if err != nil {
+			return err
+		}
+		if drift {
+			return &DriftError{Paths: []string{outputPath}}
+		}
+		return nil
 	}
 
-	return nil
+	return writeOutput(outputPath, []byte(result), mode)
+}
+
+// renderFile reads and renders the template file, stdin is used if the path is empty, see also readTemplate
+func (r *renderer) renderFile(inputPath string) (string, error) {
+	t, err := r.readTemplate(inputPath)
+	if err != nil {
+		return "", err
+	}
+	return r.renderTemplate(t)
 }
 
-// Clone returns a new copy of the renderer modified with the optional configurators
+// Clone returns a new copy of the renderer modified with the optional configurators,
+// the parameters are copied, so the clones can be used concurrently
 func (r *renderer) Clone(configurators ...func(*config.Config)) Renderer {
+	return r.clone(configurators...)
+}
+
+func (r *renderer) clone(configurators ...func(*config.Config)) *renderer {
+	configuration := r.Configuration()
+	// the parameters are copied, so e.g. the sprig 'set' function in a template doesn't affect the other clones
+	configuration.Parameters = parameters.Parameters(configuration.Parameters).Copy()
 	clone := &renderer{
-		Renderer: base.NewWithConfig(r.Configuration()),
+		Renderer: base.NewWithConfig(configuration),
+		extra:    r.extra,
 	}
+	clone.bindFunctions()
 	clone.Reconfigure(configurators...)
 	logrus.Debugf("cloned renderer: %+v", clone.String())
 	return clone
@@ -248,52 +272,3 @@ func NetFunctions() template.FuncMap {
 		"cidrSubnetSizes": CidrSubnetSizes,
 	}
 }
-
-// TODO move to files package
-type dirEntry struct {
-	path      string
-	name      string
-	extension string
-}
-
-// TODO move to files package
-func dirTree(input string) (entries []dirEntry, err error) {
-	err = filepath.Walk(input, func(path string, info os.FileInfo, dirErr error) error {
-		if dirErr != nil {
-			logrus.Errorf("error '%v' on path '%s'", dirErr, path)
-			return dirErr
-		}
-
-		logrus.Debugf("Discovered path: '%s'", path)
-
-		if !info.IsDir() {
-			logrus.Tracef("  dir  : '%s'", filepath.Dir(path))
-			logrus.Tracef("  name : '%s'", info.Name())
-			logrus.Tracef("  ext  : '%s'", filepath.Ext(path))
-
-			entry := dirEntry{
-				path:      filepath.Dir(path),
-				name:      info.Name(),
-				extension: filepath.Ext(path),
-			}
-			entries = append(entries, entry)
-		}
-		return nil
-	})
-	if err != nil {
-		return entries, errors.Wrapf(err, "can't walk the directory tree '%s'", input)
-	}
-
-	return entries, nil
-}
-
-func trimExtension(file dirEntry, extensions []string) (new dirEntry) {
-	new = file
-	for _, ext := range extensions {
-		if file.extension == ext {
-			new.name = strings.TrimSuffix(file.name, ext)
-			new.extension = filepath.Ext(new.name)
-		}
-	}
-	return
-}
@@ -179,6 +179,30 @@ third: otherer`
 	})
 }
 
+func TestRenderer_NamedRender_RenderOverride_Nested(t *testing.T) {
+	Run(t, Test{
+		name: "render render with override inside render with override",
+		f: func(tt Test) {
+			input := `{{ .outer | render .override }}`
+			expected := `outer: other, inner: other`
+			params := parameters.Parameters{
+				"outer": "outer: {{ .value }}, inner: {{ .inner | render }}",
+				"inner": "{{ .value }}",
+				"value": "some",
+				"override": map[string]interface{}{
+					"value": "other",
+				},
+			}
+
+			result, err := New(WithParameters(params)).NamedRender(tt.name, input)
+
+			assert.NoError(t, err, tt.name)
+			assert.Equal(t, expected, result, tt.name)
+			assert.Equal(t, 0, CountProblems(tt.logHook))
+		},
+	})
+}
+
 func TestRenderer_NamedRender_Func(t *testing.T) {
 	Run(t, Test{
 		name: "parse func",
@@ -247,6 +271,21 @@ func TestRenderer_Render_Validate_Default(t *testing.T) {
 	})
 }
 
+func TestRenderer_NewWithOptions(t *testing.T) {
+	Run(t, Test{
+		name: "options are not part of the parameters",
+		f: func(tt Test) {
+			params := map[string]interface{}{"value": "some"}
+			r := NewWithOptions([]Option{WithIgnorePatterns("*.bak")}, WithParameters(params))
+			r.Configure(WithIgnorePatterns("*.swp"))
+
+			assert.Equal(t, params, r.Configuration().Parameters, tt.name)
+			assert.Equal(t, params, r.Clone().Configuration().Parameters, tt.name)
+			assert.Equal(t, []string{"*.bak", "*.swp"}, r.Clone().(*renderer).extra.ignorePatterns, tt.name)
+		},
+	})
+}
+
 type Test struct {
 	name    string
 	f       func(tt Test)
@@ -0,0 +1,350 @@
+package renderer
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/VirtusLab/render/renderer/parameters"
+	"github.com/fsnotify/fsnotify"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// DefaultDebounce is the default time the Watcher waits for more changes before re-rendering
+const DefaultDebounce = 200 * time.Millisecond
+
+// Watcher renders the files and re-renders the affected outputs whenever the templates,
+// the files read with the readFile function or the configuration files change
+type Watcher struct {
+	newRenderer func() (Renderer, error)
+	configPaths []string
+	debounce    time.Duration
+}
+
+// NewWatcher creates a new Watcher, the newRenderer function is called on start
+// and whenever any of the configuration files change, so the parameters can be read again
+func NewWatcher(newRenderer func() (Renderer, error), configPaths ...string) *Watcher {
+	return &Watcher{
+		newRenderer: newRenderer,
+		configPaths: configPaths,
+		debounce:    DefaultDebounce,
+	}
+}
+
+// WithDebounce sets the time to wait for more changes before re-rendering
+func (w *Watcher) WithDebounce(debounce time.Duration) *Watcher {
+	w.debounce = debounce
+	return w
+}
+
+// WatchFile renders the file and re-renders it on changes until the stop channel is closed, see also FileRender
+func (w *Watcher) WatchFile(inputPath, outputPath string, stop <-chan struct{}) error {
+	if inputPath == "" || outputPath == "" {
+		return errors.New("the watch mode requires an input and an output file")
+	}
+	logrus.Infof("Watching '%s' -> '%s'", inputPath, outputPath)
+	return w.watch(func(r *renderer) ([]dirTask, error) {
+		return r.fileTasks(inputPath, outputPath)
+	}, "", stop)
+}
+
+// fileTasks plans the single file task, the file is still watched if the front matter skips it, see also dirTasks
+func (r *renderer) fileTasks(inputPath, outputPath string) ([]dirTask, error) {
+	task := dirTask{inputPath: inputPath, outputPath: outputPath}
+	if !r.extra.frontMatter {
+		return []dirTask{task}, nil
+	}
+	t, err := r.readTemplate(inputPath)
+	if err != nil {
+		return nil, err
+	}
+	task.mode = t.mode()
+	task.skip = t.front != nil && t.front.skip
+	return []dirTask{task}, nil
+}
+
+// WatchDir renders the directory and re-renders the affected files on changes
+// until the stop channel is closed, see also DirRender
+func (w *Watcher) WatchDir(inputDir, outputDir string, stop <-chan struct{}) error {
+	logrus.Infof("Watching '%s' -> '%s'", inputDir, outputDir)
+	return w.watch(func(r *renderer) ([]dirTask, error) {
+		return r.dirTasks(inputDir, outputDir, nil)
+	}, inputDir, stop)
+}
+
+// watchState holds the last rendering state, the paths are absolute
+type watchState struct {
+	renderer     *renderer
+	tasks        map[string]dirTask  // by the input path
+	dependencies map[string][]string // the files read by the templates, by the input path
+	outputs      map[string]bool     // the written outputs, to skip their change events
+	watched      map[string]bool     // the watched directories
+	notifier     *fsnotify.Watcher
+	scanTasks    func(*renderer) ([]dirTask, error)
+	inputDir     string
+}
+
+func (w *Watcher) watch(scanTasks func(*renderer) ([]dirTask, error), inputDir string, stop <-chan struct{}) error {
+	notifier, err := fsnotify.NewWatcher()
+	if err != nil {
+		return errors.Wrap(err, "can't create a file system watcher")
+	}
+	defer func() { _ = notifier.Close() }()
+
+	state := &watchState{
+		tasks:        make(map[string]dirTask),
+		dependencies: make(map[string][]string),
+		outputs:      make(map[string]bool),
+		watched:      make(map[string]bool),
+		notifier:     notifier,
+		scanTasks:    scanTasks,
+	}
+	if len(inputDir) > 0 {
+		state.inputDir, err = filepath.Abs(inputDir)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+	}
+
+	configs := make(map[string]bool)
+	err = w.watchConfigs(state, configs)
+	if err != nil {
+		return err
+	}
+
+	err = w.reload(state)
+	if _, ok := err.(FileErrors); ok {
+		logrus.Errorf("Can't render: %v", err)
+	} else if err != nil {
+		return err
+	}
+
+	changed := make(map[string]bool)
+	timer := time.NewTimer(w.debounce)
+	timer.Stop()
+	for {
+		select {
+		case <-stop:
+			logrus.Infof("Watching stopped")
+			return nil
+		case event, ok := <-notifier.Events:
+			if !ok {
+				return nil
+			}
+			if event.Op == fsnotify.Chmod || !(configs[event.Name] || state.relevant(event.Name)) {
+				continue
+			}
+			logrus.Debugf("Change detected: %s", event)
+			changed[event.Name] = true
+			timer.Reset(w.debounce)
+		case err, ok := <-notifier.Errors:
+			if !ok {
+				return nil
+			}
+			logrus.Errorf("File system watcher error: %v", err)
+		case <-timer.C:
+			paths := sortedKeys(changed)
+			changed = make(map[string]bool)
+			logrus.Infof("Changed:\n\t%s", strings.Join(paths, "\n\t"))
+
+			reload := false
+			for _, p := range paths {
+				reload = reload || configs[p]
+			}
+			if reload {
+				err = w.watchConfigs(state, configs)
+				if err == nil {
+					err = w.reload(state)
+				}
+			} else {
+				err = state.update(paths)
+			}
+			if err != nil {
+				logrus.Errorf("Can't render: %v", err)
+			}
+		}
+	}
+}
+
+// watchConfigs watches the configuration files and the files they import, see also parameters.ConfigFiles
+func (w *Watcher) watchConfigs(state *watchState, configs map[string]bool) error {
+	configPaths, err := parameters.ConfigFiles(w.configPaths)
+	if err != nil {
+		// the error is reported by the reload, at least the given files are watched
+		logrus.Debugf("Can't list the imported configuration files: %v", err)
+		configPaths = make([]string, len(w.configPaths))
+		for i, configPath := range w.configPaths {
+			_, configPaths[i] = parameters.FormatOf(configPath)
+		}
+	}
+	for _, configPath := range configPaths {
+		absPath, err := filepath.Abs(configPath)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		configs[absPath] = true
+		state.watchFile(absPath)
+	}
+	return nil
+}
+
+// reload creates a new renderer and renders all the files
+func (w *Watcher) reload(state *watchState) error {
+	r, err := w.newRenderer()
+	if err != nil {
+		return errors.Wrap(err, "can't create the renderer")
+	}
+	clone, ok := r.Clone().(*renderer)
+	if !ok {
+		return errors.Errorf("unexpected renderer type: '%T'", r)
+	}
+	state.renderer = clone
+	state.tasks = make(map[string]dirTask)
+	return state.update(nil)
+}
+
+// update scans the tasks and renders the new ones and the ones affected by the changed paths
+func (state *watchState) update(changed []string) error {
+	tasks, err := state.scanTasks(state.renderer)
+	if err != nil {
+		return err
+	}
+	if len(state.inputDir) > 0 {
+		state.watchTree(state.inputDir)
+	}
+
+	isChanged := make(map[string]bool, len(changed))
+	for _, p := range changed {
+		isChanged[p] = true
+	}
+
+	var fileErrors FileErrors
+	scanned := make(map[string]dirTask, len(tasks))
+	for _, task := range tasks {
+		inputPath, err := filepath.Abs(task.inputPath)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		scanned[inputPath] = task
+
+		_, known := state.tasks[inputPath]
+		affected := !known || isChanged[inputPath]
+		for _, dependency := range state.dependencies[inputPath] {
+			affected = affected || isChanged[dependency]
+		}
+		if !affected {
+			continue
+		}
+
+		state.watchFile(inputPath)
+		err = state.render(inputPath, task)
+		if err != nil {
+			fileErrors = append(fileErrors, &FileError{Path: task.inputPath, Err: err})
+		}
+	}
+	state.tasks = scanned
+
+	if len(fileErrors) > 0 {
+		return fileErrors
+	}
+	return nil
+}
+
+// relevant checks if the path is an input, a dependency of any of the inputs or is in the input directory
+func (state *watchState) relevant(absPath string) bool {
+	if state.outputs[absPath] {
+		return false
+	}
+	if _, ok := state.tasks[absPath]; ok {
+		return true
+	}
+	if len(state.inputDir) > 0 && strings.HasPrefix(absPath, state.inputDir+string(filepath.Separator)) {
+		return true
+	}
+	for _, dependencies := range state.dependencies {
+		for _, dependency := range dependencies {
+			if dependency == absPath {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// render renders a single task and records the files read by the template
+func (state *watchState) render(inputPath string, task dirTask) error {
+	var dependencies []string
+	r := state.renderer.clone()
+	r.Configure(func(c *extraConfig) {
+		c.onReadFile = func(absPath string) {
+			dependencies = append(dependencies, absPath)
+		}
+	})
+
+	var content string
+	var err error
+	if !task.copy && !task.skip {
+		content, err = r.renderFile(task.inputPath)
+	}
+	state.dependencies[inputPath] = dependencies
+	for _, dependency := range dependencies {
+		state.watchFile(dependency)
+	}
+	if err != nil {
+		return err
+	}
+	if task.skip {
+		logrus.Infof("Skipping '%s', as set in the front matter", task.inputPath)
+		return nil
+	}
+
+	outputPath, err := filepath.Abs(task.outputPath)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	state.outputs[outputPath] = true
+	return task.write(content)
+}
+
+// watchFile watches the parent directory, so the editors replacing the files are supported
+func (state *watchState) watchFile(absPath string) {
+	state.watchDir(filepath.Dir(absPath))
+}
+
+// watchTree watches all the directories in the tree
+func (state *watchState) watchTree(root string) {
+	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err == nil && info.IsDir() {
+			state.watchDir(path)
+		}
+		return nil
+	})
+	if err != nil {
+		logrus.Warnf("Can't watch the directory tree '%s': %v", root, err)
+	}
+}
+
+func (state *watchState) watchDir(dir string) {
+	if state.watched[dir] {
+		return
+	}
+	err := state.notifier.Add(dir)
+	if err != nil {
+		logrus.Warnf("Can't watch the directory '%s': %v", dir, err)
+		return
+	}
+	logrus.Debugf("Watching the directory '%s'", dir)
+	state.watched[dir] = true
+}
+
+func sortedKeys(set map[string]bool) []string {
+	keys := make([]string, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
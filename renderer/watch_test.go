@@ -0,0 +1,60 @@
+package renderer
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/VirtusLab/render/renderer/parameters"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWatcher_WatchDir(t *testing.T) {
+	inputDir, outputDir := tempDirs(t)
+	defer cleanup(inputDir, outputDir)
+	dependency := filepath.Join(outputDir, "dependency.txt")
+	writeTree(t, outputDir, map[string]string{"dependency.txt": "first"})
+	writeTree(t, inputDir, map[string]string{
+		"a.tmpl": `{{ .value }} {{ readFile "` + filepath.ToSlash(dependency) + `" }}`,
+		"b.tmpl": "{{ .value }}",
+	})
+	renderedDir := filepath.Join(outputDir, "rendered")
+
+	configPath := filepath.Join(outputDir, "config.yaml")
+	writeTree(t, outputDir, map[string]string{"config.yaml": "value: some"})
+	newRenderer := func() (Renderer, error) {
+		params, err := parameters.FromFiles([]string{configPath})
+		return New(WithParameters(params)), err
+	}
+
+	stop := make(chan struct{})
+	done := make(chan error)
+	go func() {
+		done <- NewWatcher(newRenderer, configPath).WithDebounce(10*time.Millisecond).WatchDir(inputDir, renderedDir, stop)
+	}()
+
+	eventually := func(expected map[string]string, msg string) {
+		assert.Eventually(t, func() bool {
+			return assert.ObjectsAreEqual(expected, readTree(t, renderedDir))
+		}, 5*time.Second, 10*time.Millisecond, msg)
+	}
+
+	eventually(map[string]string{"a": "some first", "b": "some"}, "initial render")
+
+	writeTree(t, inputDir, map[string]string{"b.tmpl": "changed {{ .value }}"})
+	eventually(map[string]string{"a": "some first", "b": "changed some"}, "template change")
+
+	assert.NoError(t, ioutil.WriteFile(dependency, []byte("second"), 0644))
+	eventually(map[string]string{"a": "some second", "b": "changed some"}, "dependency change")
+
+	writeTree(t, inputDir, map[string]string{"sub/c.tmpl": "{{ .value }}"})
+	eventually(map[string]string{"a": "some second", "b": "changed some", "sub/c": "some"}, "new template")
+
+	assert.NoError(t, ioutil.WriteFile(configPath, []byte("value: other"), 0644))
+	eventually(map[string]string{"a": "other second", "b": "changed other", "sub/c": "other"}, "configuration change")
+
+	close(stop)
+	assert.NoError(t, <-done)
+}